@@ -81,7 +81,7 @@ func runServe(cmd *cobra.Command, args []string) {
 		repos.Credentials,
 		repos.Users,
 		cfg.EncryptionKey,
-		logger,
+		service.WithLogger(logger),
 	)
 
 	credentialService := service.NewCredentialService(
@@ -281,7 +281,7 @@ func (h *Handler) RecalculateSize(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.bucketService.RecalculateBucketSize(r.Context(), bucketID, userID, h.encryptionKey); err != nil {
+	if _, err := h.bucketService.RecalculateBucketSize(r.Context(), bucketID, userID, h.encryptionKey); err != nil {
 		if errors.Is(err, service.ErrBucketNotFound) {
 			h.respondError(w, "Bucket not found", http.StatusNotFound)
 			return
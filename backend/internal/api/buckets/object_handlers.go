@@ -2,12 +2,14 @@ package buckets
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"bucketbird/backend/internal/middleware"
 	"bucketbird/backend/internal/service"
@@ -19,8 +21,46 @@ import (
 const maxMultipartUploadSize int64 = 5 * 1024 * 1024 * 1024 // 5 GiB
 
 type YouTubeImportRequest struct {
-	URL               string `json:"url"`
-	DestinationPrefix string `json:"destinationPrefix"`
+	URL                           string                  `json:"url"`
+	DestinationPrefix             string                  `json:"destinationPrefix"`
+	SkipShorts                    bool                    `json:"skipShorts"`
+	VerifyChecksum                bool                    `json:"verifyChecksum"`
+	ImportAfter                   time.Time               `json:"importAfter"`
+	AudioOnly                     bool                    `json:"audioOnly"`
+	Quality                       string                  `json:"quality"`
+	Concurrency                   int                     `json:"concurrency"`
+	BandwidthLimit                int64                   `json:"bandwidthLimit"`
+	OverwritePolicy               service.OverwritePolicy `json:"overwritePolicy"`
+	Tags                          map[string]string       `json:"tags"`
+	DryRun                        bool                    `json:"dryRun"`
+	StallTimeoutSeconds           int                     `json:"stallTimeoutSeconds"`
+	SanitizeMode                  string                  `json:"sanitizeMode"`
+	FilenameReplacementChar       string                  `json:"filenameReplacementChar"`
+	MaxFilenameLengthBytes        int                     `json:"maxFilenameLengthBytes"`
+	OverwriteOnTitleMatch         bool                    `json:"overwriteOnTitleMatch"`
+	PoToken                       string                  `json:"poToken"`
+	VisitorData                   string                  `json:"visitorData"`
+	AllowSeparateStreams          bool                    `json:"allowSeparateStreams"`
+	FallbackTitleMatch            bool                    `json:"fallbackTitleMatch"`
+	Shuffle                       bool                    `json:"shuffle"`
+	Reverse                       bool                    `json:"reverse"`
+	StoreVideoStats               bool                    `json:"storeVideoStats"`
+	BurnSubtitles                 bool                    `json:"burnSubtitles"`
+	SubtitleLanguage              string                  `json:"subtitleLanguage"`
+	WaitForLiveStream             bool                    `json:"waitForLiveStream"`
+	LiveStreamPollIntervalSeconds int                     `json:"liveStreamPollIntervalSeconds"`
+	ContentDisposition            string                  `json:"contentDisposition"`
+	CacheControl                  string                  `json:"cacheControl"`
+	MultipartChunkSizeMB          int                     `json:"multipartChunkSizeMb"`
+	ServerSideEncryption          string                  `json:"serverSideEncryption"`
+	SSEKMSKeyID                   string                  `json:"sseKmsKeyId"`
+	ObjectLockEnabled             bool                    `json:"objectLockEnabled"`
+	ObjectLockMode                string                  `json:"objectLockMode"`
+	ObjectLockRetainUntil         time.Time               `json:"objectLockRetainUntil"`
+	PreferHDR                     bool                    `json:"preferHdr"`
+	MaxBitrateKbps                int                     `json:"maxBitrateKbps"`
+	ImportNote                    string                  `json:"importNote"`
+	Debug                         bool                    `json:"debug"`
 }
 
 // ListObjects lists objects in a bucket
@@ -187,6 +227,55 @@ func (h *Handler) ImportYouTube(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	input := service.YouTubeImportInput{
+		URL:                           req.URL,
+		DestinationPrefix:             req.DestinationPrefix,
+		SkipShorts:                    req.SkipShorts,
+		VerifyChecksum:                req.VerifyChecksum,
+		ImportAfter:                   req.ImportAfter,
+		AudioOnly:                     req.AudioOnly,
+		Quality:                       req.Quality,
+		Concurrency:                   req.Concurrency,
+		BandwidthLimit:                req.BandwidthLimit,
+		OverwritePolicy:               req.OverwritePolicy,
+		Tags:                          req.Tags,
+		DryRun:                        req.DryRun,
+		StallTimeoutSeconds:           req.StallTimeoutSeconds,
+		SanitizeMode:                  req.SanitizeMode,
+		MaxFilenameLengthBytes:        req.MaxFilenameLengthBytes,
+		OverwriteOnTitleMatch:         req.OverwriteOnTitleMatch,
+		PoToken:                       req.PoToken,
+		VisitorData:                   req.VisitorData,
+		AllowSeparateStreams:          req.AllowSeparateStreams,
+		FallbackTitleMatch:            req.FallbackTitleMatch,
+		Shuffle:                       req.Shuffle,
+		Reverse:                       req.Reverse,
+		StoreVideoStats:               req.StoreVideoStats,
+		BurnSubtitles:                 req.BurnSubtitles,
+		SubtitleLanguage:              req.SubtitleLanguage,
+		WaitForLiveStream:             req.WaitForLiveStream,
+		LiveStreamPollIntervalSeconds: req.LiveStreamPollIntervalSeconds,
+		ContentDisposition:            req.ContentDisposition,
+		CacheControl:                  req.CacheControl,
+		MultipartChunkSizeMB:          req.MultipartChunkSizeMB,
+		ServerSideEncryption:          req.ServerSideEncryption,
+		SSEKMSKeyID:                   req.SSEKMSKeyID,
+		ObjectLockEnabled:             req.ObjectLockEnabled,
+		ObjectLockMode:                req.ObjectLockMode,
+		ObjectLockRetainUntil:         req.ObjectLockRetainUntil,
+		PreferHDR:                     req.PreferHDR,
+		MaxBitrateKbps:                req.MaxBitrateKbps,
+		ImportNote:                    req.ImportNote,
+		Debug:                         req.Debug,
+	}
+	if req.FilenameReplacementChar != "" {
+		if utf8.RuneCountInString(req.FilenameReplacementChar) != 1 {
+			h.respondError(w, "filenameReplacementChar must be a single character", http.StatusBadRequest)
+			return
+		}
+		input.FilenameReplacementChar, _ = utf8.DecodeRuneInString(req.FilenameReplacementChar)
+	}
+
 	stream := r.URL.Query().Get("stream") == "1"
 	if stream {
 		flusher, ok := w.(http.Flusher)
@@ -208,12 +297,8 @@ func (h *Handler) ImportYouTube(w http.ResponseWriter, r *http.Request) {
 
 		result, importErr := h.bucketService.ImportYouTube(
 			r.Context(),
-			bucketID,
-			userID,
-			service.YouTubeImportInput{
-				URL:               req.URL,
-				DestinationPrefix: req.DestinationPrefix,
-			},
+			h.bucketService.BucketFor(bucketID, userID),
+			input,
 			h.encryptionKey,
 			progressFn,
 		)
@@ -233,18 +318,26 @@ func (h *Handler) ImportYouTube(w http.ResponseWriter, r *http.Request) {
 
 	result, importErr := h.bucketService.ImportYouTube(
 		r.Context(),
-		bucketID,
-		userID,
-		service.YouTubeImportInput{
-			URL:               req.URL,
-			DestinationPrefix: req.DestinationPrefix,
-		},
+		h.bucketService.BucketFor(bucketID, userID),
+		input,
 		h.encryptionKey,
 		nil,
 	)
 	if importErr != nil {
 		h.logger.Error("youtube import failed", slog.Any("error", importErr))
-		h.respondError(w, fmt.Sprintf("YouTube import failed: %v", importErr), http.StatusBadRequest)
+		status := http.StatusBadRequest
+		var quotaErr *service.ErrQuotaExceeded
+		switch {
+		case errors.Is(importErr, service.ErrBucketNotFound):
+			status = http.StatusNotFound
+		case errors.Is(importErr, service.ErrImportRateLimited):
+			status = http.StatusTooManyRequests
+		case errors.Is(importErr, service.ErrStorageUnavailable):
+			status = http.StatusServiceUnavailable
+		case errors.As(importErr, &quotaErr):
+			status = http.StatusInsufficientStorage
+		}
+		h.respondError(w, fmt.Sprintf("YouTube import failed: %v", importErr), status)
 		return
 	}
 
@@ -428,6 +428,13 @@ func (r *pgBucketRepository) UpdateSize(ctx context.Context, id uuid.UUID, sizeB
 	})
 }
 
+func (r *pgBucketRepository) AddSize(ctx context.Context, id uuid.UUID, deltaBytes int64) error {
+	return r.q.AddBucketSize(ctx, sqlc.AddBucketSizeParams{
+		ID:        uuidToPgtype(id),
+		SizeBytes: deltaBytes,
+	})
+}
+
 func (r *pgBucketRepository) Delete(ctx context.Context, id, userID uuid.UUID) error {
 	return r.q.DeleteBucket(ctx, sqlc.DeleteBucketParams{
 		ID:     uuidToPgtype(id),
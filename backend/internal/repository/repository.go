@@ -43,6 +43,7 @@ type BucketRepository interface {
 	GetByName(ctx context.Context, userID uuid.UUID, name string) (*BucketWithCredential, error)
 	Update(ctx context.Context, id, userID uuid.UUID, description *string) error
 	UpdateSize(ctx context.Context, id uuid.UUID, sizeBytes int64) error
+	AddSize(ctx context.Context, id uuid.UUID, deltaBytes int64) error
 	Delete(ctx context.Context, id, userID uuid.UUID) error
 }
 
@@ -11,6 +11,22 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const addBucketSize = `-- name: AddBucketSize :exec
+UPDATE buckets
+SET size_bytes = GREATEST(size_bytes + $2, 0), updated_at = NOW()
+WHERE id = $1
+`
+
+type AddBucketSizeParams struct {
+	ID        pgtype.UUID `json:"id"`
+	SizeBytes int64       `json:"size_bytes"`
+}
+
+func (q *Queries) AddBucketSize(ctx context.Context, arg AddBucketSizeParams) error {
+	_, err := q.db.Exec(ctx, addBucketSize, arg.ID, arg.SizeBytes)
+	return err
+}
+
 const deleteBucket = `-- name: DeleteBucket :exec
 DELETE FROM buckets WHERE id = $1 AND user_id = $2
 `
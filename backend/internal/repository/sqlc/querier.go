@@ -11,6 +11,7 @@ import (
 )
 
 type Querier interface {
+	AddBucketSize(ctx context.Context, arg AddBucketSizeParams) error
 	CreateCredential(ctx context.Context, arg CreateCredentialParams) (Credential, error)
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	DeleteBucket(ctx context.Context, arg DeleteBucketParams) error
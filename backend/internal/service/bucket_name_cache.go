@@ -0,0 +1,111 @@
+package service
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	bucketNameCacheTTL     = 30 * time.Second
+	bucketNameCacheMaxSize = 1024
+)
+
+type bucketNameCacheKey struct {
+	bucketID uuid.UUID
+	userID   uuid.UUID
+}
+
+type bucketNameCacheEntry struct {
+	key       bucketNameCacheKey
+	name      string
+	expiresAt time.Time
+}
+
+// bucketNameCache is a small LRU cache of bucket names with a fixed TTL, so code paths that
+// resolve the same bucket repeatedly don't hit the database every time
+type bucketNameCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	maxSize int
+	order   *list.List // most recently used at the front
+	entries map[bucketNameCacheKey]*list.Element
+}
+
+func newBucketNameCache(ttl time.Duration, maxSize int) *bucketNameCache {
+	return &bucketNameCache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		order:   list.New(),
+		entries: make(map[bucketNameCacheKey]*list.Element),
+	}
+}
+
+func (c *bucketNameCache) get(bucketID, userID uuid.UUID) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[bucketNameCacheKey{bucketID, userID}]
+	if !ok {
+		return "", false
+	}
+	entry := elem.Value.(*bucketNameCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, entry.key)
+		return "", false
+	}
+	c.order.MoveToFront(elem)
+	return entry.name, true
+}
+
+func (c *bucketNameCache) set(bucketID, userID uuid.UUID, name string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := bucketNameCacheKey{bucketID, userID}
+	expiresAt := time.Now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*bucketNameCacheEntry)
+		entry.name = name
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&bucketNameCacheEntry{key: key, name: name, expiresAt: expiresAt})
+	for c.order.Len() > c.maxSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*bucketNameCacheEntry).key)
+	}
+}
+
+// flush drops every cached entry for the bucket, regardless of user
+func (c *bucketNameCache) flush(bucketID uuid.UUID) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, elem := range c.entries {
+		if key.bucketID == bucketID {
+			c.order.Remove(elem)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// FlushBucketNameCache forgets the cached name of a bucket, e.g. after it was renamed or deleted
+func (s *BucketService) FlushBucketNameCache(bucketID uuid.UUID) {
+	s.bucketNames.flush(bucketID)
+}
@@ -77,11 +77,13 @@ type FolderResult struct {
 
 // ListObjects lists objects in a bucket with optional prefix
 func (s *BucketService) ListObjects(ctx context.Context, bucketID, userID uuid.UUID, prefix string, encryptionKey []byte) ([]BucketObject, error) {
+	ref := s.BucketFor(bucketID, userID)
+
 	// Check if user is a demo user FIRST
 	user, err := s.users.GetByID(ctx, userID)
 	if err == nil && user.IsDemo {
 		// For demo users, get bucket name and return static demo data
-		bucketName, err := s.getBucketName(ctx, bucketID, userID)
+		bucketName, err := s.getBucketName(ctx, ref)
 		if err != nil {
 			return nil, err
 		}
@@ -89,12 +91,12 @@ func (s *BucketService) ListObjects(ctx context.Context, bucketID, userID uuid.U
 	}
 
 	// For regular users, proceed with normal flow
-	bucketName, err := s.getBucketName(ctx, bucketID, userID)
+	bucketName, err := s.getBucketName(ctx, ref)
 	if err != nil {
 		return nil, err
 	}
 
-	store, err := s.GetObjectStore(ctx, bucketID, userID, encryptionKey)
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
 	if err != nil {
 		return nil, err
 	}
@@ -244,44 +246,41 @@ func (s *BucketService) SearchObjects(ctx context.Context, bucketID, userID uuid
 
 // UploadObject uploads an object to a bucket
 func (s *BucketService) UploadObject(ctx context.Context, bucketID, userID uuid.UUID, key string, body io.Reader, contentType string, encryptionKey []byte) error {
-	bucketName, err := s.getBucketName(ctx, bucketID, userID)
+	ref := s.BucketFor(bucketID, userID)
+
+	bucketName, err := s.getBucketName(ctx, ref)
 	if err != nil {
 		return err
 	}
 
-	store, err := s.GetObjectStore(ctx, bucketID, userID, encryptionKey)
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
 	if err != nil {
 		return err
 	}
 
-	if err := store.PutObject(ctx, bucketName, key, body, contentType, nil); err != nil {
+	if _, err := store.PutObject(ctx, bucketName, key, body, contentType, nil); err != nil {
 		return err
 	}
 
-	// Update bucket size asynchronously (don't block on errors)
-	go func() {
-		if err := s.recalculateBucketSize(context.Background(), bucketID, userID, encryptionKey); err != nil {
-			s.logger.Error("failed to update bucket size after upload", slog.Any("error", err), slog.String("bucket_id", bucketID.String()))
-		}
-	}()
+	s.recalculateBucketSizeAsync(ref, encryptionKey, "upload")
 
 	return nil
 }
 
 // PresignObject generates a presigned URL for an object
 func (s *BucketService) PresignObject(ctx context.Context, bucketID, userID uuid.UUID, input PresignInput, encryptionKey []byte) (*PresignOutput, error) {
-	// Check if user is a demo user
-	user, err := s.users.GetByID(ctx, userID)
-	if err == nil && user.IsDemo {
-		return nil, ErrDemoRestriction
+	ref := s.BucketFor(bucketID, userID)
+
+	if err := s.rejectDemoUser(ctx, userID); err != nil {
+		return nil, err
 	}
 
-	bucketName, err := s.getBucketName(ctx, bucketID, userID)
+	bucketName, err := s.getBucketName(ctx, ref)
 	if err != nil {
 		return nil, err
 	}
 
-	store, err := s.GetObjectStore(ctx, bucketID, userID, encryptionKey)
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
 	if err != nil {
 		return nil, err
 	}
@@ -306,18 +305,18 @@ func (s *BucketService) PresignObject(ctx context.Context, bucketID, userID uuid
 
 // GetObjectMetadata retrieves metadata for an object
 func (s *BucketService) GetObjectMetadata(ctx context.Context, bucketID, userID uuid.UUID, key string, encryptionKey []byte) (*ObjectMetadata, error) {
-	// Check if user is a demo user
-	user, err := s.users.GetByID(ctx, userID)
-	if err == nil && user.IsDemo {
-		return nil, ErrDemoRestriction
+	ref := s.BucketFor(bucketID, userID)
+
+	if err := s.rejectDemoUser(ctx, userID); err != nil {
+		return nil, err
 	}
 
-	bucketName, err := s.getBucketName(ctx, bucketID, userID)
+	bucketName, err := s.getBucketName(ctx, ref)
 	if err != nil {
 		return nil, err
 	}
 
-	store, err := s.GetObjectStore(ctx, bucketID, userID, encryptionKey)
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
 	if err != nil {
 		return nil, err
 	}
@@ -349,18 +348,18 @@ func (s *BucketService) GetObjectMetadata(ctx context.Context, bucketID, userID
 
 // ProxyObject retrieves an object for proxying/download
 func (s *BucketService) ProxyObject(ctx context.Context, bucketID, userID uuid.UUID, key string, encryptionKey []byte) (*ProxiedObject, error) {
-	// Check if user is a demo user
-	user, err := s.users.GetByID(ctx, userID)
-	if err == nil && user.IsDemo {
-		return nil, ErrDemoRestriction
+	ref := s.BucketFor(bucketID, userID)
+
+	if err := s.rejectDemoUser(ctx, userID); err != nil {
+		return nil, err
 	}
 
-	bucketName, err := s.getBucketName(ctx, bucketID, userID)
+	bucketName, err := s.getBucketName(ctx, ref)
 	if err != nil {
 		return nil, err
 	}
 
-	store, err := s.GetObjectStore(ctx, bucketID, userID, encryptionKey)
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
 	if err != nil {
 		return nil, err
 	}
@@ -384,12 +383,14 @@ func (s *BucketService) ProxyObject(ctx context.Context, bucketID, userID uuid.U
 
 // CreateFolder creates an empty folder (0-byte object with trailing slash)
 func (s *BucketService) CreateFolder(ctx context.Context, bucketID, userID uuid.UUID, name string, prefix *string, encryptionKey []byte) (*FolderResult, error) {
-	bucketName, err := s.getBucketName(ctx, bucketID, userID)
+	ref := s.BucketFor(bucketID, userID)
+
+	bucketName, err := s.getBucketName(ctx, ref)
 	if err != nil {
 		return nil, err
 	}
 
-	store, err := s.GetObjectStore(ctx, bucketID, userID, encryptionKey)
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
 	if err != nil {
 		return nil, err
 	}
@@ -413,12 +414,14 @@ func (s *BucketService) CreateFolder(ctx context.Context, bucketID, userID uuid.
 
 // DeleteObjects deletes multiple objects
 func (s *BucketService) DeleteObjects(ctx context.Context, bucketID, userID uuid.UUID, keys []string, encryptionKey []byte) (*DeleteObjectsResult, error) {
-	bucketName, err := s.getBucketName(ctx, bucketID, userID)
+	ref := s.BucketFor(bucketID, userID)
+
+	bucketName, err := s.getBucketName(ctx, ref)
 	if err != nil {
 		return nil, err
 	}
 
-	store, err := s.GetObjectStore(ctx, bucketID, userID, encryptionKey)
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
 	if err != nil {
 		return nil, err
 	}
@@ -465,12 +468,7 @@ func (s *BucketService) DeleteObjects(ctx context.Context, bucketID, userID uuid
 		}, err
 	}
 
-	// Update bucket size asynchronously (don't block on errors)
-	go func() {
-		if err := s.recalculateBucketSize(context.Background(), bucketID, userID, encryptionKey); err != nil {
-			s.logger.Error("failed to update bucket size after delete", slog.Any("error", err), slog.String("bucket_id", bucketID.String()))
-		}
-	}()
+	s.recalculateBucketSizeAsync(ref, encryptionKey, "delete")
 
 	return &DeleteObjectsResult{
 		Deleted: keys,
@@ -480,12 +478,14 @@ func (s *BucketService) DeleteObjects(ctx context.Context, bucketID, userID uuid
 
 // RenameObject renames an object (copy + delete)
 func (s *BucketService) RenameObject(ctx context.Context, bucketID, userID uuid.UUID, sourceKey, destinationKey string, encryptionKey []byte) (*OperationResult, error) {
-	bucketName, err := s.getBucketName(ctx, bucketID, userID)
+	ref := s.BucketFor(bucketID, userID)
+
+	bucketName, err := s.getBucketName(ctx, ref)
 	if err != nil {
 		return nil, err
 	}
 
-	store, err := s.GetObjectStore(ctx, bucketID, userID, encryptionKey)
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
 	if err != nil {
 		return nil, err
 	}
@@ -590,12 +590,14 @@ func (s *BucketService) RenameObject(ctx context.Context, bucketID, userID uuid.
 
 // CopyObject copies an object
 func (s *BucketService) CopyObject(ctx context.Context, bucketID, userID uuid.UUID, sourceKey, destinationKey string, encryptionKey []byte) (*OperationResult, error) {
-	bucketName, err := s.getBucketName(ctx, bucketID, userID)
+	ref := s.BucketFor(bucketID, userID)
+
+	bucketName, err := s.getBucketName(ctx, ref)
 	if err != nil {
 		return nil, err
 	}
 
-	store, err := s.GetObjectStore(ctx, bucketID, userID, encryptionKey)
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
 	if err != nil {
 		return nil, err
 	}
@@ -665,18 +667,18 @@ func (s *BucketService) CopyObject(ctx context.Context, bucketID, userID uuid.UU
 
 // ZipFolder creates a zip archive of a folder
 func (s *BucketService) ZipFolder(ctx context.Context, bucketID, userID uuid.UUID, prefix string, encryptionKey []byte) (io.ReadCloser, string, error) {
-	// Check if user is a demo user
-	user, err := s.users.GetByID(ctx, userID)
-	if err == nil && user.IsDemo {
-		return nil, "", ErrDemoRestriction
+	ref := s.BucketFor(bucketID, userID)
+
+	if err := s.rejectDemoUser(ctx, userID); err != nil {
+		return nil, "", err
 	}
 
-	bucketName, err := s.getBucketName(ctx, bucketID, userID)
+	bucketName, err := s.getBucketName(ctx, ref)
 	if err != nil {
 		return nil, "", err
 	}
 
-	store, err := s.GetObjectStore(ctx, bucketID, userID, encryptionKey)
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
 	if err != nil {
 		return nil, "", err
 	}
@@ -776,27 +778,115 @@ func awsTimeValue(t *time.Time) time.Time {
 	return *t
 }
 
+// rejectDemoUser returns ErrDemoRestriction for demo users, whose buckets only hold demo data
+func (s *BucketService) rejectDemoUser(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if err == nil && user.IsDemo {
+		return ErrDemoRestriction
+	}
+	return nil
+}
+
+// recalculateBucketSizeAsync updates the bucket size in the background after a write, logging
+// failures instead of returning them. reason completes the log message, e.g. "upload".
+func (s *BucketService) recalculateBucketSizeAsync(ref BucketRef, encryptionKey []byte, reason string) {
+	go func() {
+		if _, err := s.recalculateBucketSize(context.Background(), ref, encryptionKey); err != nil {
+			s.logger.Error("failed to update bucket size after "+reason, slog.Any("error", err), slog.String("bucket_id", ref.BucketID.String()))
+		}
+	}()
+}
+
 // recalculateBucketSize calculates and updates the bucket size in the database
-func (s *BucketService) recalculateBucketSize(ctx context.Context, bucketID, userID uuid.UUID, encryptionKey []byte) error {
-	bucketName, err := s.getBucketName(ctx, bucketID, userID)
+func (s *BucketService) recalculateBucketSize(ctx context.Context, ref BucketRef, encryptionKey []byte) (int64, error) {
+	bucketName, err := s.getBucketName(ctx, ref)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	store, err := s.GetObjectStore(ctx, bucketID, userID, encryptionKey)
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	totalSize, err := store.CalculateBucketSize(ctx, bucketName)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.UpdateSize(ctx, ref.BucketID, totalSize); err != nil {
+		return 0, err
+	}
+	return totalSize, nil
+}
+
+// recalculateBucketSizeDelta adjusts the stored bucket size by deltaBytes without re-scanning
+// the bucket. The addition happens in a single UPDATE, so concurrent callers don't lose updates;
+// recalculateBucketSize remains the source of truth and corrects any drift.
+func (s *BucketService) recalculateBucketSizeDelta(ctx context.Context, ref BucketRef, deltaBytes int64) error {
+	if deltaBytes == 0 {
+		return nil
+	}
+
+	// Resolving the name verifies that the bucket belongs to the user
+	if _, err := s.getBucketName(ctx, ref); err != nil {
+		return err
+	}
+
+	return s.buckets.AddSize(ctx, ref.BucketID, deltaBytes)
+}
+
+// checkBucketQuota returns an *ErrQuotaExceeded when the bucket has already reached its quota
+func (s *BucketService) checkBucketQuota(ctx context.Context, ref BucketRef) error {
+	if s.bucketQuotaBytes <= 0 {
+		return nil
+	}
+
+	bucket, err := s.Get(ctx, ref.BucketID, ref.UserID)
 	if err != nil {
 		return err
 	}
+	if bucket.SizeBytes >= s.bucketQuotaBytes {
+		return &ErrQuotaExceeded{Used: bucket.SizeBytes, Quota: s.bucketQuotaBytes}
+	}
+	return nil
+}
 
-	return s.UpdateSize(ctx, bucketID, totalSize)
+// quotaExceededError builds the error for a write the storage backend rejected for lack of space
+func (s *BucketService) quotaExceededError(ctx context.Context, ref BucketRef) error {
+	quotaErr := &ErrQuotaExceeded{Quota: s.bucketQuotaBytes}
+	if bucket, err := s.Get(ctx, ref.BucketID, ref.UserID); err == nil {
+		quotaErr.Used = bucket.SizeBytes
+	}
+	return quotaErr
+}
+
+// RecalculateBucketSize scans the bucket, stores its size and returns it. Imports keep the size
+// up to date themselves; this corrects it after changes made outside BucketBird, such as manual
+// deletions or uploads straight to the storage backend.
+func (s *BucketService) RecalculateBucketSize(ctx context.Context, bucketID, userID uuid.UUID, encryptionKey []byte) (int64, error) {
+	return s.recalculateBucketSize(ctx, s.BucketFor(bucketID, userID), encryptionKey)
 }
 
-// RecalculateBucketSize is a public wrapper for recalculateBucketSize
-func (s *BucketService) RecalculateBucketSize(ctx context.Context, bucketID, userID uuid.UUID, encryptionKey []byte) error {
-	return s.recalculateBucketSize(ctx, bucketID, userID, encryptionKey)
+// CalculatePrefixSize returns the total size of the objects under prefix, e.g. to see how much
+// storage one channel's import prefix uses. Unlike RecalculateBucketSize it stores nothing.
+func (s *BucketService) CalculatePrefixSize(ctx context.Context, bucketID, userID uuid.UUID, prefix string, encryptionKey []byte) (int64, error) {
+	ref := s.BucketFor(bucketID, userID)
+
+	prefix, err := normalizeObjectPrefix(prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	bucketName, err := s.getBucketName(ctx, ref)
+	if err != nil {
+		return 0, err
+	}
+
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
+	if err != nil {
+		return 0, err
+	}
+
+	return store.CalculatePrefixSize(ctx, bucketName, prefix)
 }
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"net/http"
+	"sync"
+	"time"
 
 	"bucketbird/backend/internal/repository"
 	"bucketbird/backend/internal/storage"
@@ -13,12 +16,156 @@ import (
 )
 
 type BucketService struct {
-	buckets       repository.BucketRepository
-	credentials   repository.CredentialRepository
-	users         repository.UserRepository
-	encryptionKey []byte
-	logger        *slog.Logger
-	youtubeClient *youtube.Client
+	buckets            repository.BucketRepository
+	credentials        repository.CredentialRepository
+	users              repository.UserRepository
+	encryptionKey      []byte
+	logger             *slog.Logger
+	youtubeClient      YouTubeClient
+	requestsPerSecond  float64
+	youtubePacer       *requestPacer
+	metadataKeyPrefix  string
+	defaultConcurrency int
+	bucketNames        *bucketNameCache
+
+	youtubeClientTimeout time.Duration
+	ffmpegPath           string
+	webhook              importWebhook
+	youtubeAPIKey        string
+	events               importEventSink
+	hooks                importHooks
+	jobs                 importJobRegistry
+
+	maxConcurrentImportsPerUser int
+	importLimiter               *UserRateLimiter
+	bucketQuotaBytes            int64
+
+	formatSelector FormatSelector
+	fileNames      FileNameStrategy
+	duplicates     DuplicateChecker
+
+	logProgress      bool
+	progressLogLevel slog.Level
+
+	persistJobs bool
+	jobPrefix   string
+
+	// indexMu serializes the read-modify-write updates of imported video indexes
+	indexMu sync.Mutex
+}
+
+// defaultMetadataKeyPrefix namespaces the user metadata BucketBird writes on objects
+const defaultMetadataKeyPrefix = "bucketbird-"
+
+// BucketServiceOption configures optional BucketService behaviour
+type BucketServiceOption func(*BucketService)
+
+// WithYouTubeClient replaces the client used for YouTube imports, e.g. with a fake in tests
+func WithYouTubeClient(c YouTubeClient) BucketServiceOption {
+	return func(s *BucketService) {
+		s.youtubeClient = c
+	}
+}
+
+// WithLogger sets the logger used by the service
+func WithLogger(l *slog.Logger) BucketServiceOption {
+	return func(s *BucketService) {
+		s.logger = l
+	}
+}
+
+// WithRequestsPerSecond limits how many requests per second the service sends through its YouTube
+// client, across all imports (0 disables the limit)
+func WithRequestsPerSecond(rps float64) BucketServiceOption {
+	return func(s *BucketService) {
+		s.requestsPerSecond = rps
+	}
+}
+
+// WithMetadataKeyPrefix changes the prefix of the user metadata keys written on imported objects
+func WithMetadataKeyPrefix(prefix string) BucketServiceOption {
+	return func(s *BucketService) {
+		s.metadataKeyPrefix = prefix
+	}
+}
+
+// WithDefaultConcurrency sets how many videos an import downloads in parallel
+func WithDefaultConcurrency(n int) BucketServiceOption {
+	return func(s *BucketService) {
+		s.defaultConcurrency = n
+	}
+}
+
+// WithYouTubeClientTimeout sets the timeout of each HTTP request of the default YouTube client,
+// including each chunk of a download; 0, the default, means no timeout
+func WithYouTubeClientTimeout(d time.Duration) BucketServiceOption {
+	return func(s *BucketService) {
+		s.youtubeClientTimeout = d
+	}
+}
+
+// WithFFmpegPath sets the ffmpeg binary used to mux separate video and audio streams
+func WithFFmpegPath(path string) BucketServiceOption {
+	return func(s *BucketService) {
+		s.ffmpegPath = path
+	}
+}
+
+// WithMaxConcurrentImportsPerUser limits how many YouTube imports a single user can run at
+// once (0, the default, means no limit). Imports over the limit fail with ErrImportRateLimited.
+func WithMaxConcurrentImportsPerUser(n int) BucketServiceOption {
+	return func(s *BucketService) {
+		s.maxConcurrentImportsPerUser = n
+	}
+}
+
+// WithBucketQuotaBytes sets the size a bucket may reach before imports into it are refused
+// (0, the default, means no quota)
+func WithBucketQuotaBytes(n int64) BucketServiceOption {
+	return func(s *BucketService) {
+		s.bucketQuotaBytes = n
+	}
+}
+
+// WithFormatSelector replaces how imports choose the format to download for each video
+func WithFormatSelector(fs FormatSelector) BucketServiceOption {
+	return func(s *BucketService) {
+		s.formatSelector = fs
+	}
+}
+
+// WithFileNameStrategy replaces how imports name the objects they create
+func WithFileNameStrategy(fs FileNameStrategy) BucketServiceOption {
+	return func(s *BucketService) {
+		s.fileNames = fs
+	}
+}
+
+// WithDuplicateChecker replaces how imports detect videos that were already imported, e.g. with
+// NoDuplicateChecker to always download them again
+func WithDuplicateChecker(dc DuplicateChecker) BucketServiceOption {
+	return func(s *BucketService) {
+		s.duplicates = dc
+	}
+}
+
+// WithProgressLogging logs every import progress event at level, for operators who want import
+// progress in their log pipeline without a progress callback
+func WithProgressLogging(level slog.Level) BucketServiceOption {
+	return func(s *BucketService) {
+		s.logProgress = true
+		s.progressLogLevel = level
+	}
+}
+
+// WithJobPersistence stores the summary of every background import job in the job's bucket,
+// under <prefix>.bucketbird/jobs/, so RestoreImportJobs can reload them after a restart. The
+// caller is responsible for calling RestoreImportJobs.
+func WithJobPersistence(prefix string) BucketServiceOption {
+	return func(s *BucketService) {
+		s.persistJobs = true
+		s.jobPrefix = prefix
+	}
 }
 
 func NewBucketService(
@@ -26,16 +173,38 @@ func NewBucketService(
 	credentials repository.CredentialRepository,
 	users repository.UserRepository,
 	encryptionKey []byte,
-	logger *slog.Logger,
+	opts ...BucketServiceOption,
 ) *BucketService {
-	return &BucketService{
-		buckets:       buckets,
-		credentials:   credentials,
-		users:         users,
-		encryptionKey: encryptionKey,
-		logger:        logger,
-		youtubeClient: &youtube.Client{},
+	s := &BucketService{
+		buckets:            buckets,
+		credentials:        credentials,
+		users:              users,
+		encryptionKey:      encryptionKey,
+		logger:             slog.Default(),
+		youtubeClient:      &youtube.Client{},
+		metadataKeyPrefix:  defaultMetadataKeyPrefix,
+		defaultConcurrency: 1,
+		ffmpegPath:         defaultFFmpegPath,
+		formatSelector:     DefaultFormatSelector{},
+		fileNames:          DefaultFileNameStrategy{},
+		bucketNames:        newBucketNameCache(bucketNameCacheTTL, bucketNameCacheMaxSize),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	s.importLimiter = NewUserRateLimiter(s.maxConcurrentImportsPerUser)
+	s.youtubePacer = newRequestPacer(s.requestsPerSecond)
+	if s.youtubeClientTimeout > 0 {
+		if client, ok := s.youtubeClient.(*youtube.Client); ok && client.HTTPClient == nil {
+			client.HTTPClient = &http.Client{Timeout: s.youtubeClientTimeout}
+		}
+	}
+	return s
+}
+
+// metadataKey returns the stored user metadata key for name
+func (s *BucketService) metadataKey(name string) string {
+	return s.metadataKeyPrefix + name
 }
 
 type CreateBucketInput struct {
@@ -151,7 +320,7 @@ func (s *BucketService) Delete(ctx context.Context, id, userID uuid.UUID, delete
 	}
 
 	if deleteRemote {
-		store, err := s.GetObjectStore(ctx, id, userID, s.encryptionKey)
+		store, err := s.GetObjectStore(ctx, s.BucketFor(id, userID), s.encryptionKey)
 		if err != nil {
 			return err
 		}
@@ -161,26 +330,42 @@ func (s *BucketService) Delete(ctx context.Context, id, userID uuid.UUID, delete
 		}
 	}
 
-	return s.buckets.Delete(ctx, id, userID)
+	if err := s.buckets.Delete(ctx, id, userID); err != nil {
+		return err
+	}
+	s.FlushBucketNameCache(id)
+	return nil
 }
 
 func (s *BucketService) UpdateSize(ctx context.Context, bucketID uuid.UUID, sizeBytes int64) error {
 	return s.buckets.UpdateSize(ctx, bucketID, sizeBytes)
 }
 
+// BucketRef identifies a bucket as seen by one of its users. Passing the pair as one value
+// keeps the two UUIDs from being swapped by accident.
+type BucketRef struct {
+	BucketID uuid.UUID
+	UserID   uuid.UUID
+}
+
+// BucketFor returns the reference to bucketID owned by userID
+func (s *BucketService) BucketFor(bucketID, userID uuid.UUID) BucketRef {
+	return BucketRef{BucketID: bucketID, UserID: userID}
+}
+
 // GetObjectStore creates an object store client for a specific bucket
-func (s *BucketService) GetObjectStore(ctx context.Context, bucketID, userID uuid.UUID, encryptionKey []byte) (*storage.ObjectStore, error) {
+func (s *BucketService) GetObjectStore(ctx context.Context, ref BucketRef, encryptionKey []byte) (storage.ObjectStoreClient, error) {
 	// Get bucket (includes credential info)
-	bucket, err := s.buckets.Get(ctx, bucketID, userID)
+	bucket, err := s.buckets.Get(ctx, ref.BucketID, ref.UserID)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
-			return nil, ErrBucketNotFound
+			return nil, &BucketNotFoundError{BucketID: ref.BucketID}
 		}
 		return nil, err
 	}
 
 	// Get credential details
-	cred, err := s.credentials.Get(ctx, bucket.CredentialID, userID)
+	cred, err := s.credentials.Get(ctx, bucket.CredentialID, ref.UserID)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			return nil, ErrCredentialNotFound
@@ -200,7 +385,7 @@ func (s *BucketService) GetObjectStore(ctx context.Context, bucketID, userID uui
 	}
 
 	// Create object store client
-	return storage.NewObjectStoreWithCredentials(
+	store, err := storage.NewObjectStoreWithCredentials(
 		ctx,
 		cred.Endpoint,
 		cred.Region,
@@ -208,16 +393,25 @@ func (s *BucketService) GetObjectStore(ctx context.Context, bucketID, userID uui
 		secretKey,
 		cred.UseSSL,
 	)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
 }
 
 // Helper to get bucket name from bucket record
-func (s *BucketService) getBucketName(ctx context.Context, bucketID, userID uuid.UUID) (string, error) {
-	bucket, err := s.buckets.Get(ctx, bucketID, userID)
+func (s *BucketService) getBucketName(ctx context.Context, ref BucketRef) (string, error) {
+	if name, ok := s.bucketNames.get(ref.BucketID, ref.UserID); ok {
+		return name, nil
+	}
+
+	bucket, err := s.buckets.Get(ctx, ref.BucketID, ref.UserID)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
-			return "", ErrBucketNotFound
+			return "", &BucketNotFoundError{BucketID: ref.BucketID}
 		}
 		return "", err
 	}
+	s.bucketNames.set(ref.BucketID, ref.UserID, bucket.Name)
 	return bucket.Name, nil
 }
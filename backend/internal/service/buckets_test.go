@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNewBucketServiceOptions(t *testing.T) {
+	client := &FakeYouTubeClient{}
+	s := NewBucketService(nil, nil, nil, nil,
+		WithYouTubeClient(client),
+		WithRequestsPerSecond(4),
+		WithMetadataKeyPrefix("archive-"),
+		WithDefaultConcurrency(3),
+	)
+
+	if s.youtubeClient != client {
+		t.Error("youtube client was not replaced")
+	}
+	if s.youtubePacer == nil || s.youtubePacer.interval != 250*time.Millisecond {
+		t.Errorf("pacer = %+v, want a 250ms interval", s.youtubePacer)
+	}
+	if got := s.metadataKey(youtubeVideoIDMetadataKey); got != "archive-video-id" {
+		t.Errorf("metadata key = %q, want %q", got, "archive-video-id")
+	}
+	if s.defaultConcurrency != 3 {
+		t.Errorf("default concurrency = %d, want 3", s.defaultConcurrency)
+	}
+}
+
+func TestNewBucketServiceDefaults(t *testing.T) {
+	s := NewBucketService(nil, nil, nil, nil)
+
+	if s.youtubeClient == nil || s.logger == nil {
+		t.Fatal("expected a default youtube client and logger")
+	}
+	if s.youtubePacer != nil {
+		t.Error("requests should not be paced without WithRequestsPerSecond")
+	}
+	if s.pacedYouTubeClient(s.youtubeClient) != s.youtubeClient {
+		t.Error("client should not be wrapped without a pacer")
+	}
+	if got := s.metadataKey(youtubeVideoIDMetadataKey); got != defaultMetadataKeyPrefix+youtubeVideoIDMetadataKey {
+		t.Errorf("metadata key = %q", got)
+	}
+}
+
+func TestRequestPacer(t *testing.T) {
+	pacer := newRequestPacer(50)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := pacer.wait(ctx); err != nil {
+			t.Fatalf("wait: %v", err)
+		}
+	}
+	// The first request goes out at once, the other three 20ms apart
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("4 requests took %v, want at least 60ms", elapsed)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	pacer.wait(ctx)
+	if err := pacer.wait(cancelled); err == nil {
+		t.Error("expected wait to return the context error")
+	}
+}
@@ -1,6 +1,11 @@
 package service
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
 
 // Common errors used across services
 var (
@@ -18,6 +23,21 @@ var (
 	ErrBucketNotFound      = errors.New("bucket not found")
 	ErrBucketAlreadyExists = errors.New("bucket already exists")
 
+	// YouTube import errors
+	ErrImportedVideoNotFound  = errors.New("imported video not found")
+	ErrDownloadStalled        = errors.New("download stalled")
+	ErrInvalidPrefix          = errors.New("invalid destination prefix")
+	ErrIncompletePoToken      = errors.New("poToken and visitorData must be provided together")
+	ErrYouTubeAPIKeyMissing   = errors.New("youtube data api key is not configured")
+	ErrImportRateLimited      = errors.New("too many concurrent imports")
+	ErrImportManifestNotFound = errors.New("import manifest not found")
+	ErrDeleteNotConfirmed     = errors.New("deletion was not confirmed")
+	ErrInvalidInput           = errors.New("invalid import input")
+	ErrStorageUnavailable     = errors.New("storage backend is unavailable")
+	ErrFFmpegNotFound         = errors.New("ffmpeg not found")
+	ErrJobNotFound            = errors.New("import job not found")
+	ErrMetadataTooLarge       = errors.New("object metadata exceeds the 2 KB S3 limit")
+
 	// Demo mode errors
 	ErrDemoRestriction = errors.New("file preview and download are not available in demo mode")
 )
@@ -55,6 +75,33 @@ func newBucketProvisionError(err error) error {
 	}
 }
 
+// BucketNotFoundError reports the bucket that could not be found. It matches ErrBucketNotFound
+// with errors.Is.
+type BucketNotFoundError struct {
+	BucketID uuid.UUID
+}
+
+func (e *BucketNotFoundError) Error() string {
+	return fmt.Sprintf("bucket %s not found", e.BucketID)
+}
+
+func (e *BucketNotFoundError) Unwrap() error {
+	return ErrBucketNotFound
+}
+
+// ErrQuotaExceeded is returned when an import would grow a bucket beyond its quota
+type ErrQuotaExceeded struct {
+	Used  int64
+	Quota int64
+}
+
+func (e *ErrQuotaExceeded) Error() string {
+	if e.Quota > 0 {
+		return fmt.Sprintf("bucket quota exceeded: %d of %d bytes used", e.Used, e.Quota)
+	}
+	return "bucket quota exceeded: storage backend is full"
+}
+
 // CredentialDiscoveryError represents a failure when listing buckets for a credential
 type CredentialDiscoveryError struct {
 	Reason string
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// ImportEventComplete is published when an import finishes, successfully or not
+const ImportEventComplete = "import.complete"
+
+// ImportEvent notifies other parts of the process about import activity. For
+// ImportEventComplete the payload is the *YouTubeImportResult, or the error if the import failed.
+type ImportEvent struct {
+	EventType string
+	BucketID  uuid.UUID
+	UserID    uuid.UUID
+	Payload   interface{}
+}
+
+type importEventSink struct {
+	mu   sync.RWMutex
+	sink chan<- ImportEvent
+}
+
+// SetEventSink publishes import events on sink; nil stops publishing. Events are dropped with a
+// warning rather than blocking an import when the channel is full, so the sink should be
+// buffered and drained promptly.
+func (s *BucketService) SetEventSink(sink chan<- ImportEvent) {
+	s.events.mu.Lock()
+	defer s.events.mu.Unlock()
+	s.events.sink = sink
+}
+
+func (s *BucketService) publishImportEvent(bucketID, userID uuid.UUID, result *YouTubeImportResult, err error) {
+	s.events.mu.RLock()
+	defer s.events.mu.RUnlock()
+	if s.events.sink == nil {
+		return
+	}
+
+	event := ImportEvent{
+		EventType: ImportEventComplete,
+		BucketID:  bucketID,
+		UserID:    userID,
+		Payload:   result,
+	}
+	if err != nil {
+		event.Payload = err
+	}
+
+	select {
+	case s.events.sink <- event:
+	default:
+		s.logger.Warn("import event sink is full, dropping event",
+			"event_type", event.EventType,
+			"bucket_id", bucketID.String(),
+		)
+	}
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// ImportHook lets callers run code around every YouTube import. BeforeImport may adjust the
+// input; returning an error aborts the import. AfterImport runs once the import has finished,
+// its error is only logged since the import itself already succeeded.
+type ImportHook interface {
+	BeforeImport(ctx context.Context, bucketID uuid.UUID, input *YouTubeImportInput) error
+	AfterImport(ctx context.Context, bucketID uuid.UUID, result *YouTubeImportResult) error
+}
+
+type importHooks struct {
+	mu    sync.RWMutex
+	hooks []ImportHook
+}
+
+// RegisterHook adds h to the hooks run by ImportYouTube, in registration order
+func (s *BucketService) RegisterHook(h ImportHook) {
+	s.hooks.mu.Lock()
+	defer s.hooks.mu.Unlock()
+	s.hooks.hooks = append(s.hooks.hooks, h)
+}
+
+func (s *BucketService) registeredHooks() []ImportHook {
+	s.hooks.mu.RLock()
+	defer s.hooks.mu.RUnlock()
+	return append([]ImportHook(nil), s.hooks.hooks...)
+}
+
+func (s *BucketService) runBeforeImportHooks(ctx context.Context, bucketID uuid.UUID, input *YouTubeImportInput) error {
+	for _, hook := range s.registeredHooks() {
+		if err := hook.BeforeImport(ctx, bucketID, input); err != nil {
+			return fmt.Errorf("import aborted by hook: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *BucketService) runAfterImportHooks(ctx context.Context, bucketID uuid.UUID, result *YouTubeImportResult) {
+	for _, hook := range s.registeredHooks() {
+		if err := hook.AfterImport(ctx, bucketID, result); err != nil {
+			s.logger.Warn("import hook failed", "bucket_id", bucketID.String(), "error", err)
+		}
+	}
+}
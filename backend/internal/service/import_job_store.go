@@ -0,0 +1,163 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"bucketbird/backend/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+// importJobsDir holds the persisted job summaries below the WithJobPersistence prefix
+const importJobsDir = ".bucketbird/jobs/"
+
+// importJobWriter persists the latest summary of one job; writes are serialized
+type importJobWriter struct {
+	mu         sync.Mutex
+	jobs       *importJobRegistry
+	job        *importJob
+	store      storage.ObjectStoreClient
+	bucketName string
+	prefix     string
+	logger     *slog.Logger
+}
+
+// importJobWriter returns a writer for a job of the bucket, or nil when persistence is disabled
+// or the bucket can't be opened. A nil writer ignores writes.
+func (s *BucketService) importJobWriter(ctx context.Context, ref BucketRef, encryptionKey []byte) *importJobWriter {
+	if !s.persistJobs {
+		return nil
+	}
+	w, err := s.newImportJobWriter(ctx, ref, encryptionKey)
+	if err != nil {
+		s.logger.Warn("import job will not be persisted", "bucket_id", ref.BucketID.String(), "error", err)
+		return nil
+	}
+	return w
+}
+
+func (s *BucketService) newImportJobWriter(ctx context.Context, ref BucketRef, encryptionKey []byte) (*importJobWriter, error) {
+	prefix, err := s.importJobsPrefix()
+	if err != nil {
+		return nil, err
+	}
+
+	bucketName, err := s.getBucketName(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &importJobWriter{
+		jobs:       &s.jobs,
+		store:      store,
+		bucketName: bucketName,
+		prefix:     prefix,
+		logger:     s.logger,
+	}, nil
+}
+
+func (w *importJobWriter) write(ctx context.Context) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data, err := json.Marshal(w.jobs.summary(w.job))
+	if err == nil {
+		key := w.prefix + w.job.id.String() + ".json"
+		_, err = w.store.PutObject(ctx, w.bucketName, key, bytes.NewReader(data), "application/json", nil)
+	}
+	if err != nil {
+		w.logger.Warn("failed to persist import job", "job_id", w.job.id.String(), "error", err)
+	}
+}
+
+func (s *BucketService) importJobsPrefix() (string, error) {
+	prefix, err := normalizeObjectPrefix(s.jobPrefix)
+	if err != nil {
+		return "", err
+	}
+	return prefix + importJobsDir, nil
+}
+
+// RestoreImportJobs reloads the jobs persisted in a bucket during the last 24 hours and
+// returns how many were restored. Jobs that were running are reported as failed.
+func (s *BucketService) RestoreImportJobs(ctx context.Context, ref BucketRef, encryptionKey []byte) (int, error) {
+	if !s.persistJobs {
+		return 0, nil
+	}
+
+	prefix, err := s.importJobsPrefix()
+	if err != nil {
+		return 0, err
+	}
+
+	bucketName, err := s.getBucketName(ctx, ref)
+	if err != nil {
+		return 0, err
+	}
+
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
+	if err != nil {
+		return 0, err
+	}
+
+	objects, err := store.ListAllObjects(ctx, bucketName, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-importJobRetention)
+	restored := 0
+	for _, obj := range objects {
+		if obj.Key == nil || path.Ext(*obj.Key) != ".json" || strings.Contains(strings.TrimPrefix(*obj.Key, prefix), "/") {
+			continue
+		}
+		if obj.LastModified != nil && obj.LastModified.Before(cutoff) {
+			continue
+		}
+
+		summary, err := readImportJobSummary(ctx, store, bucketName, *obj.Key)
+		if err != nil {
+			if isNotFoundError(err) {
+				continue
+			}
+			return restored, err
+		}
+		if summary.JobID == uuid.Nil || summary.StartedAt.Before(cutoff) {
+			continue
+		}
+		if s.jobs.restore(ref.BucketID, ref.UserID, *summary) {
+			restored++
+		}
+	}
+	return restored, nil
+}
+
+func readImportJobSummary(ctx context.Context, store storage.ObjectStoreClient, bucketName, key string) (*ImportJobSummary, error) {
+	obj, err := store.GetObject(ctx, bucketName, key)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Body.Close()
+
+	var summary ImportJobSummary
+	if err := json.NewDecoder(obj.Body).Decode(&summary); err != nil {
+		return nil, fmt.Errorf("decode import job %s: %w", key, err)
+	}
+	return &summary, nil
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Import job states
+const (
+	ImportJobRunning   = "running"
+	ImportJobFinished  = "finished"
+	ImportJobFailed    = "failed"
+	ImportJobCancelled = "cancelled"
+)
+
+// importJobRetention is how long finished jobs are kept for ListImportJobs and
+// GetImportJobStatus
+const importJobRetention = 24 * time.Hour
+
+// importJobStatusBuffer is how many status updates a job buffers for a slow reader before it
+// starts dropping the oldest ones
+const importJobStatusBuffer = 64
+
+// ImportJobStatus is a snapshot of a background import. Progress is the most recent progress
+// event; Result and Error are set once the job has finished.
+type ImportJobStatus struct {
+	State    string                `json:"state"`
+	Progress YouTubeImportProgress `json:"progress"`
+	Result   *YouTubeImportResult  `json:"result,omitempty"`
+	Error    string                `json:"error,omitempty"`
+}
+
+// ImportJobSummary describes a running or recently finished import job. FinishedAt is zero
+// while the job is running.
+type ImportJobSummary struct {
+	JobID      uuid.UUID `json:"jobId"`
+	State      string    `json:"state"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	Imported   int       `json:"imported"`
+	Failed     int       `json:"failed"`
+	TotalBytes int64     `json:"totalBytes"`
+	Kind       string    `json:"kind,omitempty"`
+}
+
+// ImportJobHandle identifies a background import; StatusCh is closed after the final status
+type ImportJobHandle struct {
+	JobID    uuid.UUID
+	StatusCh <-chan ImportJobStatus
+}
+
+type importJob struct {
+	id       uuid.UUID
+	bucketID uuid.UUID
+	userID   uuid.UUID
+	status   ImportJobStatus
+	statusCh chan ImportJobStatus
+	cancel   context.CancelFunc
+	writer   *importJobWriter
+
+	startedAt  time.Time
+	finishedAt time.Time
+	// The progress events carry the running totals of the import
+	kind       string
+	imported   int
+	failed     int
+	totalBytes int64
+}
+
+func (j *importJob) summary() ImportJobSummary {
+	summary := ImportJobSummary{
+		JobID:      j.id,
+		State:      j.status.State,
+		StartedAt:  j.startedAt,
+		FinishedAt: j.finishedAt,
+		Imported:   j.imported,
+		Failed:     j.failed,
+		TotalBytes: j.totalBytes,
+		Kind:       j.kind,
+	}
+	if result := j.status.Result; result != nil {
+		summary.Imported = result.Imported
+		summary.Failed = len(result.Errors)
+		summary.TotalBytes = result.TotalBytes
+		summary.Kind = result.Kind
+	}
+	return summary
+}
+
+// importJobRegistry tracks the background imports of the process
+type importJobRegistry struct {
+	mu   sync.Mutex
+	jobs map[uuid.UUID]*importJob
+}
+
+func (r *importJobRegistry) start(bucketID, userID uuid.UUID, cancel context.CancelFunc, writer *importJobWriter) *importJob {
+	job := &importJob{
+		id:        uuid.New(),
+		bucketID:  bucketID,
+		userID:    userID,
+		status:    ImportJobStatus{State: ImportJobRunning},
+		statusCh:  make(chan ImportJobStatus, importJobStatusBuffer),
+		cancel:    cancel,
+		writer:    writer,
+		startedAt: time.Now(),
+	}
+	if writer != nil {
+		writer.job = job
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.jobs == nil {
+		r.jobs = make(map[uuid.UUID]*importJob)
+	}
+	r.prune()
+	r.jobs[job.id] = job
+	job.publish()
+	return job
+}
+
+func (r *importJobRegistry) get(userID, jobID uuid.UUID) (ImportJobStatus, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune()
+	job, ok := r.jobs[jobID]
+	if !ok || job.userID != userID {
+		return ImportJobStatus{}, false
+	}
+	return job.status, true
+}
+
+func (r *importJobRegistry) list(bucketID uuid.UUID) []ImportJobSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune()
+	summaries := make([]ImportJobSummary, 0)
+	for _, job := range r.jobs {
+		if job.bucketID == bucketID {
+			summaries = append(summaries, job.summary())
+		}
+	}
+	return summaries
+}
+
+// prune forgets the jobs that finished more than importJobRetention ago. The caller must hold
+// the lock.
+func (r *importJobRegistry) prune() {
+	cutoff := time.Now().Add(-importJobRetention)
+	for id, job := range r.jobs {
+		if !job.finishedAt.IsZero() && job.finishedAt.Before(cutoff) {
+			delete(r.jobs, id)
+		}
+	}
+}
+
+// progress records a progress event of the job and publishes it
+func (r *importJobRegistry) progress(job *importJob, event YouTubeImportProgress) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job.status.Progress = event
+	if event.Kind != "" {
+		job.kind = event.Kind
+	}
+	job.imported = max(job.imported, event.Imported)
+	job.failed = max(job.failed, event.Failed)
+	job.totalBytes = max(job.totalBytes, event.TotalBytes)
+	job.publish()
+}
+
+// cancel stops a running job of the user and marks it cancelled
+func (r *importJobRegistry) cancel(userID, jobID uuid.UUID) (*importJob, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[jobID]
+	if !ok || job.userID != userID || job.status.State != ImportJobRunning {
+		return nil, false
+	}
+	job.cancel()
+	job.status.State = ImportJobCancelled
+	job.publish()
+	return job, true
+}
+
+// restore registers a job loaded from its persisted summary. A job that was still running has
+// been interrupted by the restart and is marked failed.
+func (r *importJobRegistry) restore(bucketID, userID uuid.UUID, summary ImportJobSummary) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.jobs == nil {
+		r.jobs = make(map[uuid.UUID]*importJob)
+	}
+	if _, ok := r.jobs[summary.JobID]; ok {
+		return false
+	}
+
+	job := &importJob{
+		id:         summary.JobID,
+		bucketID:   bucketID,
+		userID:     userID,
+		status:     ImportJobStatus{State: summary.State},
+		startedAt:  summary.StartedAt,
+		finishedAt: summary.FinishedAt,
+		kind:       summary.Kind,
+		imported:   summary.Imported,
+		failed:     summary.Failed,
+		totalBytes: summary.TotalBytes,
+	}
+	if job.status.State == ImportJobRunning {
+		job.status.State = ImportJobFailed
+		job.status.Error = "interrupted by a server restart"
+	}
+	if job.finishedAt.IsZero() {
+		job.finishedAt = time.Now()
+	}
+	r.jobs[job.id] = job
+	return true
+}
+
+func (r *importJobRegistry) summary(job *importJob) ImportJobSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return job.summary()
+}
+
+// finish records the final status of the job and closes its status channel. A cancelled job
+// stays cancelled even though the import returns an error.
+func (r *importJobRegistry) finish(job *importJob, result *YouTubeImportResult, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job.finishedAt = time.Now()
+	job.status.Result = result
+	if job.status.State == ImportJobCancelled {
+		if err != nil {
+			job.status.Error = err.Error()
+		}
+	} else if err != nil {
+		job.status.State = ImportJobFailed
+		job.status.Error = err.Error()
+	} else {
+		job.status.State = ImportJobFinished
+	}
+	job.publish()
+	close(job.statusCh)
+}
+
+// publish sends the current status, dropping the oldest buffered one when the channel is full.
+// The registry lock makes the job the only sender.
+func (j *importJob) publish() {
+	for {
+		select {
+		case j.statusCh <- j.status:
+			return
+		default:
+		}
+		select {
+		case <-j.statusCh:
+		default:
+		}
+	}
+}
+
+// ImportYouTubeAsync validates the input and runs ImportYouTube in the background, detached from ctx
+func (s *BucketService) ImportYouTubeAsync(ctx context.Context, ref BucketRef, input YouTubeImportInput, encryptionKey []byte) (*ImportJobHandle, error) {
+	if err := validationError(input.Validate()); err != nil {
+		return nil, err
+	}
+
+	writer := s.importJobWriter(ctx, ref, encryptionKey)
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	job := s.jobs.start(ref.BucketID, ref.UserID, cancel, writer)
+	go func() {
+		defer cancel()
+		writer.write(jobCtx)
+
+		result, err := s.ImportYouTube(jobCtx, ref, input, encryptionKey, func(event YouTubeImportProgress) {
+			s.jobs.progress(job, event)
+			switch event.Stage {
+			case StageStarting, StageDownloaded, StageSkipped, StageUnavailable, StageError:
+				writer.write(jobCtx)
+			}
+		})
+		s.jobs.finish(job, result, err)
+		// The job context is cancelled when the job was, but the final state must still be written
+		writer.write(context.WithoutCancel(jobCtx))
+	}()
+
+	return &ImportJobHandle{JobID: job.id, StatusCh: job.statusCh}, nil
+}
+
+// GetImportJobStatus returns the current status of a job the user started with
+// ImportYouTubeAsync
+func (s *BucketService) GetImportJobStatus(userID, jobID uuid.UUID) (ImportJobStatus, error) {
+	status, ok := s.jobs.get(userID, jobID)
+	if !ok {
+		return ImportJobStatus{}, ErrJobNotFound
+	}
+	return status, nil
+}
+
+// CancelImportJob stops a running job the user started with ImportYouTubeAsync. The import
+// emits a final "cancelled" progress event and the job's state becomes ImportJobCancelled.
+func (s *BucketService) CancelImportJob(ctx context.Context, userID, jobID uuid.UUID) error {
+	job, ok := s.jobs.cancel(userID, jobID)
+	if !ok {
+		return ErrJobNotFound
+	}
+	// Persist the cancellation now, in case the process stops before the import winds down
+	job.writer.write(context.WithoutCancel(ctx))
+	return nil
+}
+
+// ListImportJobs returns the running and recently finished import jobs of a bucket, newest
+// first. Finished jobs are kept for 24 hours or until the process restarts.
+func (s *BucketService) ListImportJobs(ctx context.Context, bucketID uuid.UUID) ([]ImportJobSummary, error) {
+	summaries := s.jobs.list(bucketID)
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].StartedAt.After(summaries[j].StartedAt)
+	})
+	return summaries, nil
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestImportJobsOfAnotherUser(t *testing.T) {
+	s := NewBucketService(nil, nil, nil, nil)
+	owner, other := uuid.New(), uuid.New()
+	_, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	job := s.jobs.start(uuid.New(), owner, cancel, nil)
+
+	if _, err := s.GetImportJobStatus(other, job.id); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("GetImportJobStatus(other) error = %v, want ErrJobNotFound", err)
+	}
+	if err := s.CancelImportJob(context.Background(), other, job.id); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("CancelImportJob(other) error = %v, want ErrJobNotFound", err)
+	}
+
+	status, err := s.GetImportJobStatus(owner, job.id)
+	if err != nil || status.State != ImportJobRunning {
+		t.Fatalf("GetImportJobStatus(owner) = %v, %v, want running", status.State, err)
+	}
+	if err := s.CancelImportJob(context.Background(), owner, job.id); err != nil {
+		t.Fatalf("CancelImportJob(owner) error = %v", err)
+	}
+	if status, _ := s.GetImportJobStatus(owner, job.id); status.State != ImportJobCancelled {
+		t.Errorf("state after cancel = %v, want %v", status.State, ImportJobCancelled)
+	}
+}
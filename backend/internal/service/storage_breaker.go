@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+
+	"bucketbird/backend/internal/storage"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+)
+
+// Circuit breaker settings for the storage backend of an import. The breaker opens when at least
+// half of the most recent calls failed, once enough calls were made to judge.
+const (
+	storageBreakerWindow      = 20
+	storageBreakerMinCalls    = 5
+	storageBreakerFailureRate = 0.5
+)
+
+// storageBreaker tracks the outcome of the latest storage calls of an import. Once open it stays
+// open: the import is abandoned rather than retried against a failing backend.
+type storageBreaker struct {
+	mu       sync.Mutex
+	outcomes [storageBreakerWindow]bool // ring buffer, true for a failed call
+	next     int
+	calls    int
+	failures int
+	open     bool
+	onOpen   func()
+}
+
+func newStorageBreaker(onOpen func()) *storageBreaker {
+	return &storageBreaker{onOpen: onOpen}
+}
+
+// allow returns ErrStorageUnavailable once the breaker is open
+func (b *storageBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.open {
+		return ErrStorageUnavailable
+	}
+	return nil
+}
+
+func (b *storageBreaker) record(failed bool) {
+	b.mu.Lock()
+	if b.calls == storageBreakerWindow {
+		if b.outcomes[b.next] {
+			b.failures--
+		}
+	} else {
+		b.calls++
+	}
+	b.outcomes[b.next] = failed
+	b.next = (b.next + 1) % storageBreakerWindow
+	if failed {
+		b.failures++
+	}
+
+	opened := false
+	if !b.open && b.calls >= storageBreakerMinCalls &&
+		float64(b.failures)/float64(b.calls) >= storageBreakerFailureRate {
+		b.open = true
+		opened = true
+	}
+	b.mu.Unlock()
+
+	if opened && b.onOpen != nil {
+		b.onOpen()
+	}
+}
+
+// breakerStore guards the HeadObject and PutObject calls of an import with a storageBreaker
+type breakerStore struct {
+	storage.ObjectStoreClient
+	breaker *storageBreaker
+}
+
+func (s *breakerStore) HeadObject(ctx context.Context, bucket, key string) (*s3.HeadObjectOutput, error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	out, err := s.ObjectStoreClient.HeadObject(ctx, bucket, key)
+	s.breaker.record(isStorageFailure(ctx, err) && !isNotFoundError(err))
+	return out, err
+}
+
+func (s *breakerStore) PutObject(ctx context.Context, bucket, key string, body io.Reader, contentType string, metadata map[string]string, opts ...storage.ObjectOption) (string, error) {
+	if err := s.breaker.allow(); err != nil {
+		return "", err
+	}
+	// A broken YouTube stream also fails the upload, but says nothing about the storage backend
+	source := &bodyErrorReader{r: body}
+	etag, err := s.ObjectStoreClient.PutObject(ctx, bucket, key, source, contentType, metadata, opts...)
+	s.breaker.record(isStorageFailure(ctx, err) && source.err == nil && !storage.IsStorageFull(err))
+	return etag, err
+}
+
+// storageFailureCodes are the S3 error codes of an overloaded or failing backend
+var storageFailureCodes = map[string]bool{
+	"SlowDown":           true,
+	"RequestTimeout":     true,
+	"InternalError":      true,
+	"ServiceUnavailable": true,
+}
+
+// isStorageFailure reports whether err says the storage backend is unhealthy: a transport error,
+// a timeout, a 5xx response or throttling, but not a client or configuration error
+func isStorageFailure(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && storageFailureCodes[apiErr.ErrorCode()] {
+		return true
+	}
+	var responseErr *awshttp.ResponseError
+	if errors.As(err, &responseErr) {
+		return responseErr.HTTPStatusCode() >= http.StatusInternalServerError
+	}
+	// Without a response the request never got an answer from the backend
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// bodyErrorReader remembers the first error other than io.EOF returned by r
+type bodyErrorReader struct {
+	r   io.Reader
+	err error
+}
+
+func (b *bodyErrorReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil && err != io.EOF && b.err == nil {
+		b.err = err
+	}
+	return n, err
+}
+
+// WriteTo passes r's io.WriterTo on to io.Copy. Errors returned by w are the storage backend's
+// and are not remembered.
+func (b *bodyErrorReader) WriteTo(w io.Writer) (int64, error) {
+	wt, ok := b.r.(io.WriterTo)
+	if !ok {
+		return io.Copy(w, struct{ io.Reader }{b})
+	}
+	dst := &writeErrorWriter{w: w}
+	n, err := wt.WriteTo(dst)
+	if err != nil && dst.err == nil && b.err == nil {
+		b.err = err
+	}
+	return n, err
+}
+
+// writeErrorWriter remembers the first error returned by w
+type writeErrorWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (w *writeErrorWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+func s3ResponseError(status int, code string) error {
+	return &smithy.OperationError{
+		ServiceID:     "S3",
+		OperationName: "PutObject",
+		Err: &awshttp.ResponseError{
+			ResponseError: &smithyhttp.ResponseError{
+				Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+				Err:      &smithy.GenericAPIError{Code: code, Message: code},
+			},
+		},
+	}
+}
+
+func TestIsStorageFailure(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"nil", context.Background(), nil, false},
+		{"server error", context.Background(), s3ResponseError(http.StatusInternalServerError, "InternalError"), true},
+		{"unavailable", context.Background(), s3ResponseError(http.StatusServiceUnavailable, "ServiceUnavailable"), true},
+		{"slow down", context.Background(), s3ResponseError(http.StatusServiceUnavailable, "SlowDown"), true},
+		{"timeout", context.Background(), fmt.Errorf("put: %w", context.DeadlineExceeded), true},
+		{"connection refused", context.Background(), &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{"access denied", context.Background(), s3ResponseError(http.StatusForbidden, "AccessDenied"), false},
+		{"invalid kms key", context.Background(), s3ResponseError(http.StatusBadRequest, "KMS.NotFoundException"), false},
+		{"object lock not enabled", context.Background(), s3ResponseError(http.StatusBadRequest, "InvalidRequest"), false},
+		{"cancelled call", cancelled, s3ResponseError(http.StatusInternalServerError, "InternalError"), false},
+		{"other error", context.Background(), errors.New("invalid argument"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isStorageFailure(tt.ctx, tt.err); got != tt.want {
+				t.Errorf("isStorageFailure(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// writerToOnly hides everything but io.WriterTo, to prove io.Copy used it
+type writerToOnly struct{ io.WriterTo }
+
+func (writerToOnly) Read([]byte) (int, error) { return 0, errors.New("read called") }
+
+func TestBodyErrorReaderWriteTo(t *testing.T) {
+	data := strings.Repeat("bucketbird", 1000)
+	progress := newProgressReader(io.NopCloser(strings.NewReader(data)), int64(len(data)), nil, WithBufferSize(512))
+	source := &bodyErrorReader{r: progress}
+
+	var dst bytes.Buffer
+	if _, err := io.Copy(&dst, writerToOnly{source}); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if dst.String() != data || progress.BytesRead() != int64(len(data)) {
+		t.Errorf("copied %d bytes, counted %d, want %d", dst.Len(), progress.BytesRead(), len(data))
+	}
+
+	// A failing destination is the storage backend's error, not the body's
+	source = &bodyErrorReader{r: newProgressReader(io.NopCloser(strings.NewReader(data)), 0, nil, WithBufferSize(512))}
+	if _, err := io.Copy(failingWriter{}, writerToOnly{source}); err == nil {
+		t.Fatal("expected the write error")
+	}
+	if source.err != nil {
+		t.Errorf("body error = %v, want nil", source.err)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("upload failed") }
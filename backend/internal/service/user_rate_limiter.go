@@ -0,0 +1,53 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// UserRateLimiter caps how many operations each user may run at the same time
+type UserRateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	active map[uuid.UUID]int
+}
+
+// NewUserRateLimiter allows limit concurrent operations per user; limit <= 0 means unlimited
+func NewUserRateLimiter(limit int) *UserRateLimiter {
+	return &UserRateLimiter{
+		limit:  limit,
+		active: make(map[uuid.UUID]int),
+	}
+}
+
+// TryAcquire takes a slot for userID without waiting and reports whether one was free. Every
+// successful call must be paired with Release.
+func (l *UserRateLimiter) TryAcquire(userID uuid.UUID) bool {
+	if l == nil || l.limit <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.active[userID] >= l.limit {
+		return false
+	}
+	l.active[userID]++
+	return true
+}
+
+// Release frees a slot taken by TryAcquire
+func (l *UserRateLimiter) Release(userID uuid.UUID) {
+	if l == nil || l.limit <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.active[userID] <= 1 {
+		delete(l.active, userID)
+		return
+	}
+	l.active[userID]--
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/kkdai/youtube/v2"
+)
+
+// FakeYouTubeClient serves videos and playlists from memory. Lookups of unknown URLs return
+// the errors the real client returns for them.
+type FakeYouTubeClient struct {
+	Videos    map[string]*youtube.Video
+	Playlists map[string]*youtube.Playlist
+	Streams   map[string]string
+	// EntryErrors fails the lookup of the playlist entries with the given IDs
+	EntryErrors map[string]error
+}
+
+var _ YouTubeClient = (*FakeYouTubeClient)(nil)
+
+func (c *FakeYouTubeClient) GetPlaylistContext(ctx context.Context, url string) (*youtube.Playlist, error) {
+	if playlist, ok := c.Playlists[url]; ok {
+		return playlist, nil
+	}
+	return nil, youtube.ErrInvalidPlaylist
+}
+
+func (c *FakeYouTubeClient) GetVideoContext(ctx context.Context, url string) (*youtube.Video, error) {
+	if video, ok := c.Videos[url]; ok {
+		return video, nil
+	}
+	return nil, youtube.ErrVideoPrivate
+}
+
+func (c *FakeYouTubeClient) VideoFromPlaylistEntryContext(ctx context.Context, entry *youtube.PlaylistEntry) (*youtube.Video, error) {
+	if err, ok := c.EntryErrors[entry.ID]; ok {
+		return nil, err
+	}
+	return c.GetVideoContext(ctx, entry.ID)
+}
+
+func (c *FakeYouTubeClient) GetStreamContext(ctx context.Context, video *youtube.Video, format *youtube.Format) (io.ReadCloser, int64, error) {
+	data, ok := c.Streams[video.ID]
+	if !ok {
+		return nil, 0, errors.New("no stream")
+	}
+	return io.NopCloser(strings.NewReader(data)), int64(len(data)), nil
+}
+
+func TestResolveYouTubeVideos(t *testing.T) {
+	client := &FakeYouTubeClient{
+		Videos: map[string]*youtube.Video{
+			"https://www.youtube.com/watch?v=single":           {ID: "single", Title: "Single"},
+			"https://www.youtube.com/watch?v=seed&list=RDseed": {ID: "seed", Title: "Seed"},
+			"first":  {ID: "first", Title: "First"},
+			"second": {ID: "second", Title: "Second"},
+		},
+		Playlists: map[string]*youtube.Playlist{
+			"https://www.youtube.com/playlist?list=PL1": {
+				ID: "PL1",
+				Videos: []*youtube.PlaylistEntry{
+					{ID: "first", Title: "First"},
+					{ID: "private", Title: "Private"},
+					{ID: "second", Title: "Second"},
+					{ID: "broken", Title: "Broken"},
+				},
+			},
+		},
+		EntryErrors: map[string]error{"broken": errors.New("connection reset")},
+	}
+	s := NewBucketService(nil, nil, nil, nil,
+		WithYouTubeClient(client),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+
+	tests := []struct {
+		name        string
+		url         string
+		wantKind    string
+		wantIDs     []string
+		unavailable int
+		failed      int
+		wantErr     bool
+	}{
+		{name: "video", url: "https://www.youtube.com/watch?v=single", wantKind: "video", wantIDs: []string{"single"}},
+		{name: "mix", url: "https://www.youtube.com/watch?v=seed&list=RDseed", wantKind: "mix", wantIDs: []string{"seed"}},
+		{name: "playlist", url: "https://www.youtube.com/playlist?list=PL1", wantKind: "playlist", wantIDs: []string{"first", "second"}, unavailable: 1, failed: 1},
+		{name: "unknown video", url: "https://www.youtube.com/watch?v=missing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &YouTubeImportResult{}
+			videos, kind, err := s.resolveYouTubeVideos(context.Background(), client, tt.url, result, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if kind != tt.wantKind {
+				t.Errorf("kind = %q, want %q", kind, tt.wantKind)
+			}
+			var ids []string
+			for _, video := range videos {
+				ids = append(ids, video.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("videos = %v, want %v", ids, tt.wantIDs)
+			}
+			if len(result.Unavailable) != tt.unavailable || len(result.Errors) != tt.failed {
+				t.Errorf("unavailable = %d, errors = %d, want %d and %d", len(result.Unavailable), len(result.Errors), tt.unavailable, tt.failed)
+			}
+		})
+	}
+}
+
+func TestImportYouTubeSearchValidation(t *testing.T) {
+	s := NewBucketService(nil, nil, nil, nil, WithYouTubeAPIKey("key"))
+	tests := []struct {
+		name       string
+		query      string
+		maxResults int
+	}{
+		{"empty query", "  ", 10},
+		{"zero results", "golang", 0},
+		{"too many results", "golang", maxYouTubeSearchResults + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.ImportYouTubeSearch(context.Background(), BucketRef{}, tt.query, tt.maxResults, YouTubeImportInput{}, nil, nil)
+			if !errors.Is(err, ErrInvalidInput) {
+				t.Fatalf("ImportYouTubeSearch() error = %v, want ErrInvalidInput", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+
+	"bucketbird/backend/internal/storage"
+
+	"github.com/kkdai/youtube/v2"
+)
+
+// DuplicateChecker reports whether a video was already imported and the key of the existing object
+type DuplicateChecker interface {
+	IsDuplicate(ctx context.Context, store storage.ObjectStoreClient, bucketName, key string, video *youtube.Video) (bool, string, error)
+}
+
+// DefaultDuplicateChecker finds a video through the prefix index, its key and the ID suffixed key
+type DefaultDuplicateChecker struct {
+	metadataKeyPrefix string
+	input             YouTubeImportInput
+	// indexed holds the IDs of the import prefix's index, nil when the prefix has none
+	indexed map[string]bool
+}
+
+func (c DefaultDuplicateChecker) IsDuplicate(ctx context.Context, store storage.ObjectStoreClient, bucketName, key string, video *youtube.Video) (bool, string, error) {
+	// Title matching looks for objects without a video ID, which the index can't list
+	if c.indexed != nil && !c.indexed[video.ID] && !c.input.FallbackTitleMatch {
+		return false, "", nil
+	}
+
+	head, err := store.HeadObject(ctx, bucketName, key)
+	if err != nil && !isNotFoundError(err) {
+		return false, "", err
+	}
+	if err == nil && c.matches(head.Metadata, video) {
+		return true, key, nil
+	}
+
+	// The suffixed name contains the video ID, so any object there is the video
+	suffixedKey := withVideoIDSuffix(key, video.ID)
+	if _, err := store.HeadObject(ctx, bucketName, suffixedKey); err == nil {
+		return true, suffixedKey, nil
+	} else if !isNotFoundError(err) {
+		return false, "", err
+	}
+	return false, "", nil
+}
+
+// matches reports whether metadata marks the object as video, by ID or with FallbackTitleMatch by title
+func (c DefaultDuplicateChecker) matches(metadata map[string]string, video *youtube.Video) bool {
+	videoIDKey := c.metadataKeyPrefix + youtubeVideoIDMetadataKey
+	if metadataMatchesYouTubeVideo(metadata, videoIDKey, video.ID) {
+		return true
+	}
+	if !c.input.FallbackTitleMatch || metadataValue(metadata, videoIDKey) != "" {
+		return false
+	}
+	title := metadataValue(metadata, c.metadataKeyPrefix+youtubeVideoTitleMetadataKey)
+	if title == "" {
+		return false
+	}
+	expected := sanitizeFileName(video.Title, c.input)
+	return expected != "" && sanitizeFileName(title, c.input) == expected
+}
+
+// NoDuplicateChecker never reports a duplicate, so every import downloads the videos again and
+// replaces the objects of earlier imports
+type NoDuplicateChecker struct{}
+
+func (NoDuplicateChecker) IsDuplicate(context.Context, storage.ObjectStoreClient, string, string, *youtube.Video) (bool, string, error) {
+	return false, "", nil
+}
+
+// duplicateChecker returns the configured checker, or the default one loaded with the prefix index
+func (s *BucketService) duplicateChecker(ctx context.Context, store storage.ObjectStoreClient, bucketName, prefix string, input YouTubeImportInput) DuplicateChecker {
+	if _, isDefault := s.duplicates.(DefaultDuplicateChecker); s.duplicates != nil && !isDefault {
+		return s.duplicates
+	}
+
+	checker := DefaultDuplicateChecker{metadataKeyPrefix: s.metadataKeyPrefix, input: input}
+	ids, err := readImportedVideoIndex(ctx, store, bucketName, prefix)
+	if err != nil {
+		// Without the index every video is looked up
+		s.logger.Warn("failed to read imported video index", "prefix", prefix, "error", err)
+		return checker
+	}
+	if ids != nil {
+		checker.indexed = make(map[string]bool, len(ids))
+		for _, id := range ids {
+			checker.indexed[id] = true
+		}
+	}
+	return checker
+}
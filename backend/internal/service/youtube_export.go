@@ -0,0 +1,435 @@
+package service
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"bucketbird/backend/internal/storage"
+)
+
+// m3u8PlaylistName is the object written next to the videos by WriteM3U8Playlist
+const m3u8PlaylistName = "playlist.m3u8"
+
+// m3u8ContentType is the registered media type for M3U8 playlists
+const m3u8ContentType = "application/vnd.apple.mpegurl"
+
+// GenerateM3U8Playlist builds an extended M3U playlist of the videos imported under prefix.
+// Entries are relative to the prefix, so the playlist works when stored next to the videos.
+func (s *BucketService) GenerateM3U8Playlist(ctx context.Context, ref BucketRef, prefix string, encryptionKey []byte) (string, error) {
+	prefix, err := normalizeObjectPrefix(prefix)
+	if err != nil {
+		return "", err
+	}
+
+	items, err := s.ListImportedYouTubeVideos(ctx, ref, prefix, encryptionKey)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	for _, item := range items {
+		duration := item.DurationSeconds
+		if duration <= 0 {
+			duration = -1
+		}
+		title := item.Title
+		if title == "" {
+			title = item.VideoID
+		}
+		title = strings.Join(strings.Fields(title), " ")
+
+		fmt.Fprintf(&b, "#EXTINF:%d,%s\n", duration, title)
+		b.WriteString(strings.TrimPrefix(item.Key, prefix))
+		b.WriteString("\n")
+	}
+
+	return b.String(), nil
+}
+
+// WriteM3U8Playlist generates the playlist for prefix and stores it as <prefix>playlist.m3u8,
+// returning the key of the written object
+func (s *BucketService) WriteM3U8Playlist(ctx context.Context, ref BucketRef, prefix string, encryptionKey []byte) (string, error) {
+	prefix, err := normalizeObjectPrefix(prefix)
+	if err != nil {
+		return "", err
+	}
+
+	playlist, err := s.GenerateM3U8Playlist(ctx, ref, prefix, encryptionKey)
+	if err != nil {
+		return "", err
+	}
+
+	bucketName, err := s.getBucketName(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
+	if err != nil {
+		return "", err
+	}
+
+	key := prefix + m3u8PlaylistName
+	if _, err := store.PutObject(ctx, bucketName, key, strings.NewReader(playlist), m3u8ContentType, nil); err != nil {
+		return "", err
+	}
+
+	s.recalculateBucketSizeAsync(ref, encryptionKey, "writing playlist")
+
+	return key, nil
+}
+
+// htmlIndexName is the object written next to the videos by GenerateHTMLIndex
+const htmlIndexName = "index.html"
+
+// htmlIndexLinkExpiry is how long the download links of an HTML index stay valid
+const htmlIndexLinkExpiry = time.Hour
+
+var htmlIndexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2rem auto; max-width: 60rem; padding: 0 1rem; }
+li { display: flex; gap: 1rem; align-items: center; margin-bottom: 1rem; }
+img { width: 160px; height: 90px; object-fit: cover; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Download links expire at {{.ExpiresAt}}.</p>
+<ul>
+{{- range .Videos}}
+<li>
+<img src="{{.ThumbnailURL}}" alt="" loading="lazy">
+<div><a href="{{.URL}}">{{.Title}}</a>{{if .Duration}} ({{.Duration}}){{end}}</div>
+</li>
+{{- end}}
+</ul>
+</body>
+</html>
+`))
+
+type htmlIndexVideo struct {
+	Title        string
+	ThumbnailURL string
+	Duration     string
+	URL          string
+}
+
+// GenerateHTMLIndex writes <prefix>index.html, a page listing the videos imported under prefix
+// with their thumbnails, durations and download links. The links are presigned and expire
+// after an hour, so the page must be regenerated to share the videos for longer.
+func (s *BucketService) GenerateHTMLIndex(ctx context.Context, ref BucketRef, prefix, title string, encryptionKey []byte) error {
+	prefix, err := normalizeObjectPrefix(prefix)
+	if err != nil {
+		return err
+	}
+
+	if err := s.rejectDemoUser(ctx, ref.UserID); err != nil {
+		return err
+	}
+
+	items, err := s.ListImportedYouTubeVideos(ctx, ref, prefix, encryptionKey)
+	if err != nil {
+		return err
+	}
+
+	bucketName, err := s.getBucketName(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
+	if err != nil {
+		return err
+	}
+
+	videos := make([]htmlIndexVideo, 0, len(items))
+	for _, item := range items {
+		presigned, err := store.PresignObject(ctx, storage.PresignInput{
+			Bucket:    bucketName,
+			Key:       item.Key,
+			Method:    http.MethodGet,
+			ExpiresIn: htmlIndexLinkExpiry,
+		})
+		if err != nil {
+			return fmt.Errorf("presign %s: %w", item.Key, err)
+		}
+
+		video := htmlIndexVideo{
+			Title:        cmp.Or(item.Title, item.VideoID),
+			ThumbnailURL: "https://i.ytimg.com/vi/" + url.PathEscape(item.VideoID) + "/mqdefault.jpg",
+			URL:          presigned.URL,
+		}
+		if item.DurationSeconds > 0 {
+			video.Duration = formatVideoDuration(item.DurationSeconds)
+		}
+		videos = append(videos, video)
+	}
+
+	var page bytes.Buffer
+	if err := htmlIndexTemplate.Execute(&page, map[string]any{
+		"Title":     cmp.Or(title, prefix, bucketName),
+		"ExpiresAt": time.Now().Add(htmlIndexLinkExpiry).UTC().Format(time.RFC1123),
+		"Videos":    videos,
+	}); err != nil {
+		return fmt.Errorf("render html index: %w", err)
+	}
+
+	if _, err := store.PutObject(ctx, bucketName, prefix+htmlIndexName, &page, "text/html; charset=utf-8", nil); err != nil {
+		return err
+	}
+
+	s.recalculateBucketSizeAsync(ref, encryptionKey, "writing html index")
+
+	return nil
+}
+
+// formatVideoDuration formats seconds as m:ss, or h:mm:ss for videos of an hour or more
+func formatVideoDuration(seconds int) string {
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// rssFeedName is the object written next to the videos by GenerateRSSFeed
+const rssFeedName = "feed.rss"
+
+// rssEnclosureExpiry is how long the enclosure links of an RSS feed stay valid, the longest
+// lifetime S3 allows for presigned URLs
+const rssEnclosureExpiry = 7 * 24 * time.Hour
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title     string       `xml:"title"`
+	Link      string       `xml:"link"`
+	GUID      rssGUID      `xml:"guid"`
+	PubDate   string       `xml:"pubDate,omitempty"`
+	Enclosure rssEnclosure `xml:"enclosure"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// GenerateRSSFeed writes <prefix>feed.rss, an RSS 2.0 feed of the videos imported under prefix,
+// most recently imported first, and returns its key. Each item's enclosure is a presigned
+// download link, so podcast clients can subscribe to the prefix. The links expire after seven
+// days, so the feed must be regenerated at least that often.
+func (s *BucketService) GenerateRSSFeed(ctx context.Context, ref BucketRef, prefix, feedTitle, feedURL string, encryptionKey []byte) (string, error) {
+	prefix, err := normalizeObjectPrefix(prefix)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.rejectDemoUser(ctx, ref.UserID); err != nil {
+		return "", err
+	}
+
+	items, err := s.ListImportedYouTubeVideos(ctx, ref, prefix, encryptionKey)
+	if err != nil {
+		return "", err
+	}
+	// The list may be shared through the context cache, so sort a copy
+	items = slices.Clone(items)
+	slices.SortStableFunc(items, func(a, b YouTubeImportedItem) int {
+		return b.ImportedAt.Compare(a.ImportedAt)
+	})
+
+	bucketName, err := s.getBucketName(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
+	if err != nil {
+		return "", err
+	}
+
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         cmp.Or(feedTitle, prefix, bucketName),
+			Link:          feedURL,
+			Description:   fmt.Sprintf("Videos imported into %s/%s", bucketName, prefix),
+			LastBuildDate: time.Now().UTC().Format(time.RFC1123Z),
+			Items:         make([]rssItem, 0, len(items)),
+		},
+	}
+	for _, item := range items {
+		presigned, err := store.PresignObject(ctx, storage.PresignInput{
+			Bucket:    bucketName,
+			Key:       item.Key,
+			Method:    http.MethodGet,
+			ExpiresIn: rssEnclosureExpiry,
+		})
+		if err != nil {
+			return "", fmt.Errorf("presign %s: %w", item.Key, err)
+		}
+
+		rss := rssItem{
+			Title: cmp.Or(item.Title, item.VideoID),
+			Link:  "https://www.youtube.com/watch?v=" + url.QueryEscape(item.VideoID),
+			GUID:  rssGUID{Value: item.VideoID},
+			Enclosure: rssEnclosure{
+				URL:    presigned.URL,
+				Length: item.SizeBytes,
+				Type:   cmp.Or(item.ContentType, "application/octet-stream"),
+			},
+		}
+		if !item.ImportedAt.IsZero() {
+			rss.PubDate = item.ImportedAt.UTC().Format(time.RFC1123Z)
+		}
+		feed.Channel.Items = append(feed.Channel.Items, rss)
+	}
+
+	var body bytes.Buffer
+	body.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&body)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(feed); err != nil {
+		return "", fmt.Errorf("encode rss feed: %w", err)
+	}
+
+	key := prefix + rssFeedName
+	if _, err := store.PutObject(ctx, bucketName, key, &body, "application/rss+xml", nil); err != nil {
+		return "", err
+	}
+
+	s.recalculateBucketSizeAsync(ref, encryptionKey, "writing rss feed")
+
+	return key, nil
+}
+
+// youtubeReportHeader lists the columns written by YouTubeImportResult.WriteCSV
+var youtubeReportHeader = []string{"VideoID", "Title", "Key", "SizeBytes", "ContentType", "DurationSeconds", "PublishedAt", "Status", "Error"}
+
+// WriteCSV writes the result as a CSV report with one row per imported, skipped, failed and
+// unavailable video. Only imported rows carry a key and size.
+func (r *YouTubeImportResult) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(youtubeReportHeader); err != nil {
+		return err
+	}
+
+	publishedAt := func(item YouTubeImportedItem) string {
+		if item.PublishedAt.IsZero() {
+			return ""
+		}
+		return item.PublishedAt.UTC().Format(time.RFC3339)
+	}
+	duration := func(item YouTubeImportedItem) string {
+		if item.DurationSeconds <= 0 {
+			return ""
+		}
+		return strconv.Itoa(item.DurationSeconds)
+	}
+
+	for _, item := range r.Items {
+		if err := cw.Write([]string{
+			item.VideoID, item.Title, item.Key, strconv.FormatInt(item.SizeBytes, 10), item.ContentType,
+			duration(item), publishedAt(item), "Imported", "",
+		}); err != nil {
+			return err
+		}
+	}
+	for _, item := range r.SkippedItems {
+		if err := cw.Write([]string{
+			item.VideoID, item.Title, "", "", item.ContentType,
+			duration(item), publishedAt(item), "Skipped", "",
+		}); err != nil {
+			return err
+		}
+	}
+	for _, importErr := range r.Errors {
+		if err := cw.Write([]string{importErr.VideoID, importErr.Title, "", "", "", "", "", "Error", importErr.Error}); err != nil {
+			return err
+		}
+	}
+	for _, importErr := range r.Unavailable {
+		if err := cw.Write([]string{importErr.VideoID, importErr.Title, "", "", "", "", "", "Unavailable", importErr.Error}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteNDJSON writes the result as newline-delimited JSON, one object per imported, skipped or
+// failed video. Each object carries a "type" of "imported", "skipped" or "error"; unavailable
+// videos are written as errors with errorKind "unavailable".
+func (r *YouTubeImportResult) WriteNDJSON(w io.Writer) error {
+	type itemLine struct {
+		Type string `json:"type"`
+		YouTubeImportedItem
+	}
+	type errorLine struct {
+		Type string `json:"type"`
+		YouTubeImportError
+	}
+
+	encoder := json.NewEncoder(w)
+	for _, item := range r.Items {
+		if err := encoder.Encode(itemLine{Type: "imported", YouTubeImportedItem: item}); err != nil {
+			return err
+		}
+	}
+	for _, item := range r.SkippedItems {
+		if err := encoder.Encode(itemLine{Type: "skipped", YouTubeImportedItem: item}); err != nil {
+			return err
+		}
+	}
+	for _, importErr := range r.Errors {
+		if err := encoder.Encode(errorLine{Type: "error", YouTubeImportError: importErr}); err != nil {
+			return err
+		}
+	}
+	for _, importErr := range r.Unavailable {
+		if importErr.ErrorKind == "" {
+			importErr.ErrorKind = "unavailable"
+		}
+		if err := encoder.Encode(errorLine{Type: "error", YouTubeImportError: importErr}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,95 +1,519 @@
 package service
 
 import (
+	"bufio"
+	"cmp"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/url"
+	"os"
+	"path"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"bucketbird/backend/internal/storage"
 
 	"github.com/google/uuid"
 	"github.com/kkdai/youtube/v2"
+	"golang.org/x/text/unicode/norm"
 )
 
+// YouTubeClient is the subset of the YouTube client used by ImportYouTube.
+// *youtube.Client satisfies it; tests can substitute a fake.
+type YouTubeClient interface {
+	GetPlaylistContext(ctx context.Context, url string) (*youtube.Playlist, error)
+	GetVideoContext(ctx context.Context, url string) (*youtube.Video, error)
+	VideoFromPlaylistEntryContext(ctx context.Context, entry *youtube.PlaylistEntry) (*youtube.Video, error)
+	GetStreamContext(ctx context.Context, video *youtube.Video, format *youtube.Format) (io.ReadCloser, int64, error)
+}
+
+// YouTubeImportInput holds the settings of a single import. The optional fields can also be
+// set with ImportOption values passed to ImportYouTube.
 type YouTubeImportInput struct {
-	URL               string
-	DestinationPrefix string
+	URL                     string
+	DestinationPrefix       string
+	SkipShorts              bool
+	VerifyChecksum          bool
+	ImportAfter             time.Time
+	AudioOnly               bool
+	Quality                 string
+	Concurrency             int
+	BandwidthLimit          int64
+	OverwritePolicy         OverwritePolicy
+	Tags                    map[string]string
+	DryRun                  bool
+	StallTimeoutSeconds     int
+	SanitizeMode            string
+	FilenameReplacementChar rune
+	MaxFilenameLengthBytes  int
+	OverwriteOnTitleMatch   bool
+	// PoToken is a proof of origin token from a browser session, set together with VisitorData
+	PoToken              string
+	VisitorData          string
+	AllowSeparateStreams bool
+	FallbackTitleMatch   bool
+	Shuffle              bool
+	Reverse              bool
+	// StoreVideoStats records the view count, and the like count when a YouTube Data API key
+	// is configured, on each imported object
+	StoreVideoStats bool
+	// BurnSubtitles renders the SubtitleLanguage subtitles (English by default) into the video
+	// with ffmpeg. The video is re-encoded to H.264 in an MP4 container.
+	BurnSubtitles    bool
+	SubtitleLanguage string
+	// WaitForLiveStream waits for running live streams to end and imports their recording,
+	// checking every LiveStreamPollIntervalSeconds (60 by default)
+	WaitForLiveStream             bool
+	LiveStreamPollIntervalSeconds int
+	// ContentDisposition is stored as the Content-Disposition header of each imported object,
+	// e.g. "attachment" to make browsers download it. {title} and {filename} are replaced with
+	// the video title and the object's file name, as in `attachment; filename="{title}.mp4"`.
+	ContentDisposition string
+	// CacheControl is stored as the Cache-Control header of each imported object, see
+	// DefaultCacheControl for typical values
+	CacheControl string
+	// MultipartChunkSizeMB is the part size of multipart uploads, used for videos larger than
+	// one part, from 5 to 128 MiB; 0 selects 10.
+	MultipartChunkSizeMB int
+	// PreferHDR picks HDR formats over SDR ones of the same resolution. By default HDR formats
+	// are picked last, as they share their quality label's resolution with the SDR ones.
+	PreferHDR bool
+	// MaxBitrateKbps skips formats above this bitrate to limit storage use. When every format
+	// exceeds it, the one with the lowest bitrate is imported anyway.
+	MaxBitrateKbps int
+	// ImportNote is a free-form note of up to 256 bytes on why the videos were imported,
+	// stored on every imported object and in the import manifest
+	ImportNote string
+	// ServerSideEncryption encrypts imported objects at rest: "AES256" for SSE-S3 or "aws:kms"
+	// for SSE-KMS with the SSEKMSKeyID key, or the account's default key when it is empty
+	ServerSideEncryption string
+	SSEKMSKeyID          string
+	// ObjectLockEnabled makes imported objects immutable (WORM) in ObjectLockMode, GOVERNANCE
+	// or COMPLIANCE, until ObjectLockRetainUntil. The bucket must have object lock enabled.
+	ObjectLockEnabled     bool
+	ObjectLockMode        string
+	ObjectLockRetainUntil time.Time
+	// Debug adds "debug" progress events with the number of reads from each video stream, to
+	// compare buffer sizes
+	Debug bool
+
+	// seenVideoIDs lists videos handled by an earlier import of the same batch, see
+	// ImportYouTubePlaylists
+	seenVideoIDs map[string]bool
+	// searchVideoIDs replaces URL resolution with a fixed list of videos, see ImportYouTubeSearch
+	searchVideoIDs []string
+}
+
+// OverwritePolicy controls what happens when a video has already been imported
+type OverwritePolicy string
+
+const (
+	// OverwriteSkipExisting keeps previously imported objects and skips the video (default)
+	OverwriteSkipExisting OverwritePolicy = "skip"
+	// OverwriteReplaceExisting downloads the video again and replaces the existing object
+	OverwriteReplaceExisting OverwritePolicy = "replace"
+)
+
+// ImportOption adjusts a YouTubeImportInput
+type ImportOption func(*YouTubeImportInput)
+
+// Apply returns a copy of the input with the given options applied
+func (in YouTubeImportInput) Apply(opts ...ImportOption) YouTubeImportInput {
+	for _, opt := range opts {
+		opt(&in)
+	}
+	return in
+}
+
+// WithAudioOnly imports only an audio stream instead of a muxed video
+func WithAudioOnly() ImportOption {
+	return func(in *YouTubeImportInput) {
+		in.AudioOnly = true
+	}
+}
+
+// WithQuality prefers formats whose quality label (e.g. "720p") or quality (e.g. "hd720") matches q
+func WithQuality(q string) ImportOption {
+	return func(in *YouTubeImportInput) {
+		in.Quality = q
+	}
+}
+
+// WithConcurrency sets how many videos are downloaded in parallel, overriding the service default
+func WithConcurrency(n int) ImportOption {
+	return func(in *YouTubeImportInput) {
+		in.Concurrency = n
+	}
+}
+
+// WithBandwidthLimit caps the download rate of each video to bps bytes per second
+func WithBandwidthLimit(bps int64) ImportOption {
+	return func(in *YouTubeImportInput) {
+		in.BandwidthLimit = bps
+	}
+}
+
+// WithOverwritePolicy sets how previously imported videos are handled
+func WithOverwritePolicy(p OverwritePolicy) ImportOption {
+	return func(in *YouTubeImportInput) {
+		in.OverwritePolicy = p
+	}
 }
 
+// WithTags applies the given S3 object tags to every imported object
+func WithTags(tags map[string]string) ImportOption {
+	return func(in *YouTubeImportInput) {
+		in.Tags = tags
+	}
+}
+
+// WithDryRun resolves the videos and target keys without downloading or uploading anything
+func WithDryRun() ImportOption {
+	return func(in *YouTubeImportInput) {
+		in.DryRun = true
+	}
+}
+
+// ImportStage is the step of an import a YouTubeImportProgress event reports. It is
+// serialized as the plain string.
+type ImportStage string
+
+// Import stages. Resolving and resolved cover the whole import; the stages from starting to
+// downloaded are reported per video and end in downloaded, skipped, unavailable or error.
+const (
+	StageResolving        ImportStage = "resolving"
+	StageResolved         ImportStage = "resolved"
+	StageStarting         ImportStage = "starting"
+	StageWaitingForStream ImportStage = "waiting-for-stream"
+	StageChecking         ImportStage = "checking"
+	StageCaptions         ImportStage = "captions"
+	StageMuxing           ImportStage = "muxing"
+	StageDownloading      ImportStage = "downloading"
+	StageUploading        ImportStage = "uploading"
+	StageDebug            ImportStage = "debug"
+	StageDownloaded       ImportStage = "downloaded"
+	StageSkipped          ImportStage = "skipped"
+	StageUnavailable      ImportStage = "unavailable"
+	StageError            ImportStage = "error"
+	StageFinished         ImportStage = "finished"
+	StageCancelled        ImportStage = "cancelled"
+)
+
 type YouTubeImportProgress struct {
-	Stage              string  `json:"stage"`
-	Kind               string  `json:"kind,omitempty"`
-	Index              int     `json:"index,omitempty"`
-	Total              int     `json:"total,omitempty"`
-	Imported           int     `json:"imported,omitempty"`
-	Failed             int     `json:"failed,omitempty"`
-	TotalBytes         int64   `json:"totalBytes,omitempty"`
-	VideoTitle         string  `json:"videoTitle,omitempty"`
-	VideoID            string  `json:"videoId,omitempty"`
-	Message            string  `json:"message,omitempty"`
-	Error              string  `json:"error,omitempty"`
-	Destination        string  `json:"destination,omitempty"`
-	BytesRead          int64   `json:"bytesRead,omitempty"`
-	TotalBytesExpected int64   `json:"totalBytesExpected,omitempty"`
-	Percent            float64 `json:"percent,omitempty"`
-	SpeedBytesPerSec   float64 `json:"speedBytesPerSec,omitempty"`
-	Skipped            bool    `json:"skipped,omitempty"`
-	SkippedCount       int     `json:"skippedCount,omitempty"`
+	Stage                   ImportStage `json:"stage"`
+	SessionID               uuid.UUID   `json:"sessionId"`
+	Kind                    string      `json:"kind,omitempty"`
+	Index                   int         `json:"index,omitempty"`
+	Total                   int         `json:"total,omitempty"`
+	Imported                int         `json:"imported,omitempty"`
+	Failed                  int         `json:"failed,omitempty"`
+	TotalBytes              int64       `json:"totalBytes,omitempty"`
+	VideoTitle              string      `json:"videoTitle,omitempty"`
+	VideoID                 string      `json:"videoId,omitempty"`
+	Author                  string      `json:"author,omitempty"`
+	Message                 string      `json:"message,omitempty"`
+	Error                   string      `json:"error,omitempty"`
+	Destination             string      `json:"destination,omitempty"`
+	BytesRead               int64       `json:"bytesRead,omitempty"`
+	TotalBytesExpected      int64       `json:"totalBytesExpected,omitempty"`
+	Percent                 float64     `json:"percent,omitempty"`
+	SpeedBytesPerSec        float64     `json:"speedBytesPerSec,omitempty"`
+	InstantSpeedBytesPerSec float64     `json:"instantSpeedBytesPerSec,omitempty"`
+	AvgSpeedBytesPerSec     float64     `json:"avgSpeedBytesPerSec,omitempty"`
+	Skipped                 bool        `json:"skipped,omitempty"`
+	SkippedCount            int         `json:"skippedCount,omitempty"`
+	UnavailableCount        int         `json:"unavailableCount,omitempty"`
+	Width                   int         `json:"width,omitempty"`
+	Height                  int         `json:"height,omitempty"`
+	BitrateKbps             int         `json:"bitrateKbps,omitempty"`
+	ReadCalls               int64       `json:"readCalls,omitempty"`
+	PeakSpeedBytesPerSec    float64     `json:"peakSpeedBytesPerSec,omitempty"`
+	MinSpeedBytesPerSec     float64     `json:"minSpeedBytesPerSec,omitempty"`
+	// PartNumber and TotalParts report multipart upload progress; TotalParts is 0 when the
+	// size of the video is unknown
+	PartNumber int `json:"partNumber,omitempty"`
+	TotalParts int `json:"totalParts,omitempty"`
+	// Timestamp is set when the event is emitted so consumers can order events received
+	// out of order; it is always serialized
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// progressTimestampLayout is RFC 3339 with millisecond precision
+const progressTimestampLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// MarshalJSON encodes Timestamp with millisecond precision
+func (p YouTubeImportProgress) MarshalJSON() ([]byte, error) {
+	type progressAlias YouTubeImportProgress
+	return json.Marshal(struct {
+		progressAlias
+		Timestamp string `json:"timestamp"`
+	}{
+		progressAlias: progressAlias(p),
+		Timestamp:     p.Timestamp.UTC().Format(progressTimestampLayout),
+	})
 }
 
 type YouTubeImportedItem struct {
-	Title       string `json:"title"`
-	Key         string `json:"key"`
-	VideoID     string `json:"videoId"`
-	SizeBytes   int64  `json:"sizeBytes"`
-	ContentType string `json:"contentType"`
+	Title       string    `json:"title"`
+	Key         string    `json:"key"`
+	VideoID     string    `json:"videoId"`
+	SizeBytes   int64     `json:"sizeBytes"`
+	ContentType string    `json:"contentType"`
+	Width       int       `json:"width,omitempty"`
+	Height      int       `json:"height,omitempty"`
+	BitrateKbps int       `json:"bitrateKbps,omitempty"`
+	PublishedAt time.Time `json:"publishedAt"`
+	ETag        string    `json:"etag,omitempty"`
+	// DurationSeconds is the video length, 0 when unknown
+	DurationSeconds int `json:"durationSeconds,omitempty"`
+	// ImportedBy and ImportedAt record who imported the object and when
+	ImportedBy string    `json:"importedBy,omitempty"`
+	ImportedAt time.Time `json:"importedAt"`
+	// Author is the name of the channel that published the video
+	Author string `json:"author,omitempty"`
+	// ViewCount and LikeCount are the video's statistics at import time, see StoreVideoStats
+	ViewCount int64 `json:"viewCount,omitempty"`
+	LikeCount int64 `json:"likeCount,omitempty"`
+	// PeakDownloadSpeedBytesPerSec and MinDownloadSpeedBytesPerSec are the fastest and slowest
+	// download speeds measured between progress reports, for diagnostics
+	PeakDownloadSpeedBytesPerSec float64 `json:"peakDownloadSpeedBytesPerSec,omitempty"`
+	MinDownloadSpeedBytesPerSec  float64 `json:"minDownloadSpeedBytesPerSec,omitempty"`
+	// AlternateFormats are the other formats the video was available in, in order of
+	// preference after the imported one
+	AlternateFormats []FormatInfo `json:"alternateFormats,omitempty"`
 }
 
 type YouTubeImportError struct {
-	Title   string `json:"title,omitempty"`
-	VideoID string `json:"videoId,omitempty"`
-	Error   string `json:"error"`
+	Title     string `json:"title,omitempty"`
+	VideoID   string `json:"videoId,omitempty"`
+	Error     string `json:"error"`
+	ErrorKind string `json:"errorKind,omitempty"`
 }
 
+// YouTubeImportResult summarises an import. DuplicatesSkipped counts videos already handled by
+// another playlist of the same ImportYouTubePlaylists batch; SkippedItems are the videos that
+// had already been imported and carry no size. BucketID, UserID and DestinationPrefix say where
+// the videos went, so the result can be logged or forwarded on its own.
 type YouTubeImportResult struct {
-	Kind       string                `json:"kind"`
-	Imported   int                   `json:"imported"`
-	Skipped    int                   `json:"skipped"`
-	TotalBytes int64                 `json:"totalBytes"`
-	Items      []YouTubeImportedItem `json:"items"`
-	Errors     []YouTubeImportError  `json:"errors"`
+	Kind              string                `json:"kind"`
+	DryRun            bool                  `json:"dryRun,omitempty"`
+	SessionID         uuid.UUID             `json:"sessionId"`
+	BucketID          uuid.UUID             `json:"bucketId"`
+	UserID            uuid.UUID             `json:"userId"`
+	DestinationPrefix string                `json:"destinationPrefix"`
+	Imported          int                   `json:"imported"`
+	Skipped           int                   `json:"skipped"`
+	ShortsSkipped     int                   `json:"shortsSkipped"`
+	OlderSkipped      int                   `json:"olderSkipped"`
+	DuplicatesSkipped int                   `json:"duplicatesSkipped,omitempty"`
+	TotalBytes        int64                 `json:"totalBytes"`
+	Items             []YouTubeImportedItem `json:"items"`
+	SkippedItems      []YouTubeImportedItem `json:"skippedItems"`
+	Errors            []YouTubeImportError  `json:"errors"`
+	Unavailable       []YouTubeImportError  `json:"unavailable"`
 }
 
 var fileNameSanitizer = regexp.MustCompile(`[^a-zA-Z0-9\-\._ ]+`)
 
+// unicodeFileNameSanitizer keeps letters and numbers of any script, so titles in e.g. Japanese
+// or Cyrillic don't collapse into the "youtube-video" fallback name.
+var unicodeFileNameSanitizer = regexp.MustCompile(`[^\p{L}\p{N}\-\._ ]+`)
+
+// Filename sanitize modes for YouTubeImportInput.SanitizeMode
+const (
+	SanitizeModeASCII   = "ascii"
+	SanitizeModeUnicode = "unicode"
+)
+
+// Metadata keys written on imported objects. They are combined with the service's
+// metadata key prefix (see WithMetadataKeyPrefix) before being stored.
 const (
-	youtubeVideoIDMetadataKey    = "bucketbird-video-id"
-	youtubeVideoTitleMetadataKey = "bucketbird-video-title"
+	youtubeVideoIDMetadataKey     = "video-id"
+	youtubeVideoTitleMetadataKey  = "video-title"
+	youtubeResolutionMetadataKey  = "resolution"
+	youtubeSHA256MetadataKey      = "sha256"
+	youtubeDurationMetadataKey    = "duration"
+	youtubeImportedByMetadataKey  = "imported-by"
+	youtubeImportedAtMetadataKey  = "imported-at"
+	youtubeChannelMetadataKey     = "channel"
+	youtubeDescriptionMetadataKey = "description"
+	youtubeViewCountMetadataKey   = "view-count"
+	youtubeLikeCountMetadataKey   = "like-count"
+	youtubeImportNoteMetadataKey  = "import-note"
+	youtubeSessionIDMetadataKey   = "session-id"
+	youtubeTagsMetadataKey        = "tags"
 )
 
+// maxImportNoteBytes is the size an ImportNote may have. It is stored as metadata, so it
+// is limited in bytes rather than characters.
+const maxImportNoteBytes = 256
+
+// maxUserMetadataBytes is the size S3 allows for the user metadata of an object, keys included
+const maxUserMetadataBytes = 2048
+
+// maxTagsMetadataBytes caps the stored tags, which share the 2 KB of user metadata with the
+// description
+const maxTagsMetadataBytes = 512
+
+// maxDescriptionMetadataBytes keeps a stored description well inside the 2 KB S3 allows for
+// all user metadata of an object
+const maxDescriptionMetadataBytes = 1024
+
+// maxImportConcurrency caps how many videos a single import downloads in parallel
+const maxImportConcurrency = 16
+
+// defaultImportBufferSize is the read buffer placed in front of YouTube streams
+// before they are uploaded; large reads keep high-latency uploads saturated.
+const defaultImportBufferSize = 8 * 1024 * 1024
+
+// Multipart upload part sizes in MiB. S3 allows parts of up to 5 GiB, but each import buffers
+// one part in memory per concurrent video, so the maximum is kept low.
+const (
+	defaultMultipartChunkSizeMB = 10
+	minMultipartChunkSizeMB     = 5
+	maxMultipartChunkSizeMB     = 128
+)
+
+// defaultMaxFilenameLengthBytes caps the sanitized title part of an imported object's name.
+// S3 limits the full key, including the prefix and extension, to 1024 bytes, so larger
+// values of YouTubeImportInput.MaxFilenameLengthBytes must leave room for both.
+const defaultMaxFilenameLengthBytes = 80
+
+// youtubeShortsMaxDuration is the length below which a video is treated as a YouTube Short.
+const youtubeShortsMaxDuration = 60 * time.Second
+
 func (s *BucketService) ImportYouTube(
 	ctx context.Context,
-	bucketID,
-	userID uuid.UUID,
+	ref BucketRef,
+	input YouTubeImportInput,
+	encryptionKey []byte,
+	progress func(YouTubeImportProgress),
+	opts ...ImportOption,
+) (*YouTubeImportResult, error) {
+	sessionID := uuid.New()
+
+	input = input.Apply(opts...)
+	if err := validationError(input.Validate()); err != nil {
+		return nil, err
+	}
+
+	if !s.importLimiter.TryAcquire(ref.UserID) {
+		return nil, ErrImportRateLimited
+	}
+	defer s.importLimiter.Release(ref.UserID)
+
+	progress = s.importProgressCallback(ctx, sessionID, progress)
+
+	result, err := s.importYouTube(ctx, ref, sessionID, input, encryptionKey, progress)
+	if err != nil && ctx.Err() != nil {
+		cancelled := YouTubeImportProgress{
+			Stage:   StageCancelled,
+			Message: "Import cancelled",
+			Error:   err.Error(),
+		}
+		if result != nil {
+			cancelled.Kind = result.Kind
+		}
+		emitProgress(progress, cancelled)
+	}
+	s.publishImportEvent(ref.BucketID, ref.UserID, result, err)
+	return result, err
+}
+
+// importProgressCallback wraps the caller's progress callback. Every event is tagged with the
+// session ID, so subscribers of several concurrent imports can tell them apart, and logged when
+// progress logging is enabled.
+func (s *BucketService) importProgressCallback(ctx context.Context, sessionID uuid.UUID, progress func(YouTubeImportProgress)) func(YouTubeImportProgress) {
+	if progress == nil && !s.logProgress {
+		return nil
+	}
+	return func(event YouTubeImportProgress) {
+		event.SessionID = sessionID
+		if s.logProgress {
+			attrs := []slog.Attr{
+				slog.String("session_id", sessionID.String()),
+				slog.String("video_id", event.VideoID),
+				slog.Int("index", event.Index),
+				slog.Int("total", event.Total),
+				slog.Float64("percent", event.Percent),
+				slog.Int64("bytes_read", event.BytesRead),
+			}
+			if event.Error != "" {
+				attrs = append(attrs, slog.String("error", event.Error))
+			}
+			s.logger.LogAttrs(ctx, s.progressLogLevel, string(event.Stage), attrs...)
+		}
+		if progress != nil {
+			progress(event)
+		}
+	}
+}
+
+// ImportYouTubeWithChannel runs ImportYouTube and delivers the progress events on ch. Events are
+// sent without blocking, so a slow reader cannot hold up the import; events that don't fit in
+// the channel's buffer are dropped and logged. The channel is not closed.
+func (s *BucketService) ImportYouTubeWithChannel(
+	ctx context.Context,
+	ref BucketRef,
+	input YouTubeImportInput,
+	encryptionKey []byte,
+	ch chan<- YouTubeImportProgress,
+	opts ...ImportOption,
+) (*YouTubeImportResult, error) {
+	var progress func(YouTubeImportProgress)
+	if ch != nil {
+		progress = func(event YouTubeImportProgress) {
+			s.emitProgressAsync(ch, event)
+		}
+	}
+	return s.ImportYouTube(ctx, ref, input, encryptionKey, progress, opts...)
+}
+
+func (s *BucketService) importYouTube(
+	ctx context.Context,
+	ref BucketRef,
+	sessionID uuid.UUID,
 	input YouTubeImportInput,
 	encryptionKey []byte,
 	progress func(YouTubeImportProgress),
 ) (*YouTubeImportResult, error) {
+	if err := s.runBeforeImportHooks(ctx, ref.BucketID, &input); err != nil {
+		return nil, err
+	}
+
 	url := strings.TrimSpace(input.URL)
-	if url == "" {
-		return nil, fmt.Errorf("youtube url is required")
+	if input.BurnSubtitles && !input.AudioOnly && !input.DryRun {
+		if err := s.checkFFmpeg(); err != nil {
+			return nil, err
+		}
+	}
+	if !input.DryRun {
+		if err := s.checkBucketQuota(ctx, ref); err != nil {
+			return nil, err
+		}
 	}
 
-	bucketName, err := s.getBucketName(ctx, bucketID, userID)
+	bucketName, err := s.getBucketName(ctx, ref)
 	if err != nil {
 		return nil, err
 	}
 
-	store, err := s.GetObjectStore(ctx, bucketID, userID, encryptionKey)
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
 	if err != nil {
 		return nil, err
 	}
@@ -99,156 +523,445 @@ func (s *BucketService) ImportYouTube(
 		client = &youtube.Client{}
 		s.youtubeClient = client
 	}
+	client = s.pacedYouTubeClient(s.withYouTubeTokens(client, input))
 
 	result := &YouTubeImportResult{
-		Kind:   "video",
-		Items:  make([]YouTubeImportedItem, 0),
-		Errors: make([]YouTubeImportError, 0),
+		Kind:         "video",
+		DryRun:       input.DryRun,
+		SessionID:    sessionID,
+		BucketID:     ref.BucketID,
+		UserID:       ref.UserID,
+		Items:        make([]YouTubeImportedItem, 0),
+		SkippedItems: make([]YouTubeImportedItem, 0),
+		Errors:       make([]YouTubeImportError, 0),
+		Unavailable:  make([]YouTubeImportError, 0),
 	}
 
-	prefix := normalizeObjectPrefix(input.DestinationPrefix)
+	prefix, err := normalizeObjectPrefix(input.DestinationPrefix)
+	if err != nil {
+		return nil, err
+	}
+	result.DestinationPrefix = prefix
 
 	emitProgress(progress, YouTubeImportProgress{
-		Stage:       "resolving",
+		Stage:       StageResolving,
 		Message:     "Resolving YouTube link",
 		Destination: prefix,
 	})
 
-	videos, kind, err := s.resolveYouTubeVideos(ctx, client, url, result, progress)
-	if err != nil {
-		return nil, err
+	var (
+		videos []*youtube.Video
+		kind   string
+	)
+	if input.searchVideoIDs != nil {
+		kind = "search"
+		videos = s.videosFromIDs(ctx, client, input.searchVideoIDs, kind, result, progress)
+	} else {
+		videos, kind, err = s.resolveYouTubeVideos(ctx, client, url, result, progress)
+		if err != nil {
+			return nil, err
+		}
 	}
 	result.Kind = kind
+
+	if input.SkipShorts && kind == "playlist" {
+		videos = filterYouTubeShorts(videos, kind, result, progress)
+	}
+	if !input.ImportAfter.IsZero() {
+		videos = filterYouTubeVideosPublishedBefore(videos, input.ImportAfter, kind, result, progress)
+	}
+	if len(input.seenVideoIDs) > 0 {
+		videos = filterYouTubeVideosSeen(videos, input.seenVideoIDs, kind, result, progress)
+	}
 	totalVideos := len(videos)
 
+	resolvedMessage := fmt.Sprintf("Found %d item(s)", totalVideos)
+	if input.Shuffle {
+		rand.Shuffle(len(videos), func(i, j int) {
+			videos[i], videos[j] = videos[j], videos[i]
+		})
+		resolvedMessage = fmt.Sprintf("Shuffled %d videos", totalVideos)
+	} else if input.Reverse {
+		slices.Reverse(videos)
+		resolvedMessage += ", importing in reverse order"
+	}
+	if kind == "mix" {
+		resolvedMessage += "; YouTube Mix playlists are generated per viewer and may only partially resolve"
+	}
+
 	emitProgress(progress, YouTubeImportProgress{
-		Stage:       "resolved",
+		Stage:       StageResolved,
 		Kind:        kind,
 		Total:       totalVideos,
-		Message:     fmt.Sprintf("Found %d item(s)", totalVideos),
+		Message:     resolvedMessage,
 		Destination: prefix,
 	})
 
+	// Give up on the whole import when the storage backend keeps failing, instead of letting
+	// every remaining video fail on its own
+	ctx, cancelRun := context.WithCancelCause(ctx)
+	defer cancelRun(nil)
+	breaker := newStorageBreaker(func() {
+		s.logger.Warn("storage backend keeps failing, aborting youtube import",
+			"bucket_id", ref.BucketID.String(),
+		)
+		cancelRun(ErrStorageUnavailable)
+	})
+
+	run := &youtubeImportRun{
+		bucket:     ref,
+		sessionID:  sessionID,
+		store:      &breakerStore{ObjectStoreClient: store, breaker: breaker},
+		bucketName: bucketName,
+		prefix:     prefix,
+		client:     client,
+		kind:       kind,
+		total:      totalVideos,
+		input:      input,
+		duplicates: s.duplicateChecker(ctx, store, bucketName, prefix, input),
+		result:     result,
+		progress:   progress,
+	}
+
+	concurrency := s.defaultConcurrency
+	if input.Concurrency > 0 {
+		concurrency = input.Concurrency
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > 1 && progress != nil {
+		var progressMu sync.Mutex
+		run.progress = func(event YouTubeImportProgress) {
+			progressMu.Lock()
+			defer progressMu.Unlock()
+			progress(event)
+		}
+	}
+
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
 	for i, video := range videos {
-		if err := ctx.Err(); err != nil {
-			return nil, err
+		if ctx.Err() != nil {
+			break
 		}
 
-		emitProgress(progress, YouTubeImportProgress{
-			Stage:      "starting",
-			Kind:       kind,
-			Index:      i + 1,
-			Total:      totalVideos,
-			VideoTitle: video.Title,
-			VideoID:    video.ID,
-			Message:    fmt.Sprintf("Downloading %q", video.Title),
-		})
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(index int, video *youtube.Video) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			s.importYouTubeVideo(ctx, run, index, video)
+		}(i+1, video)
+	}
+	wg.Wait()
+
+	// Index the videos even when the import was cancelled, since the uploaded ones stay
+	if len(result.Items) > 0 && !input.DryRun {
+		if err := s.updateImportedVideoIndex(context.WithoutCancel(ctx), store, bucketName, prefix, result.Items); err != nil {
+			s.logger.Warn("failed to update imported video index",
+				"bucket_id", ref.BucketID.String(),
+				"prefix", prefix,
+				"error", err,
+			)
+		}
+	}
 
-		progressFn := func(bytesRead int64, total int64, speed float64) {
-			emitProgress(progress, YouTubeImportProgress{
-				Stage:              "downloading",
-				Kind:               kind,
-				Index:              i + 1,
-				Total:              totalVideos,
-				VideoTitle:         video.Title,
-				VideoID:            video.ID,
-				BytesRead:          bytesRead,
-				TotalBytesExpected: total,
-				Percent:            computePercent(bytesRead, total),
-				SpeedBytesPerSec:   speed,
-			})
+	if ctx.Err() != nil {
+		return nil, context.Cause(ctx)
+	}
+
+	if result.Imported > 0 && !input.DryRun {
+		s.recalculateBucketSizeAsync(ref, encryptionKey, "youtube import")
+	}
+
+	emitProgress(run.progress, YouTubeImportProgress{
+		Stage:            StageFinished,
+		Kind:             kind,
+		Imported:         result.Imported,
+		Failed:           len(result.Errors),
+		SkippedCount:     result.Skipped,
+		UnavailableCount: len(result.Unavailable),
+		Total:            totalVideos,
+		TotalBytes:       result.TotalBytes,
+		Message:          "Import complete",
+	})
+
+	s.runAfterImportHooks(ctx, ref.BucketID, result)
+
+	s.notifyImportWebhook(result)
+
+	return result, nil
+}
+
+// ImportYouTubePlaylists imports several playlists one after the other into the same bucket.
+// A video that appears in more than one playlist is only downloaded for the first of them.
+// progress receives the index of the playlist in urls along with each event. On failure the
+// results of the playlists imported so far are returned with the error.
+func (s *BucketService) ImportYouTubePlaylists(
+	ctx context.Context,
+	ref BucketRef,
+	urls []string,
+	input YouTubeImportInput,
+	encryptionKey []byte,
+	progress func(int, YouTubeImportProgress),
+) ([]*YouTubeImportResult, error) {
+	seenVideoIDs := make(map[string]bool)
+	results := make([]*YouTubeImportResult, 0, len(urls))
+
+	for i, url := range urls {
+		var playlistProgress func(YouTubeImportProgress)
+		if progress != nil {
+			playlistProgress = func(event YouTubeImportProgress) {
+				progress(i, event)
+			}
 		}
 
-		item, skipped, downloadErr := s.downloadYouTubeVideo(ctx, store, bucketName, prefix, client, video, progressFn)
-		if downloadErr != nil {
-			s.logger.Warn("failed to import youtube video",
-				"title", video.Title,
-				"video_id", video.ID,
-				"error", downloadErr,
-			)
-			emitProgress(progress, YouTubeImportProgress{
-				Stage:      "error",
-				Kind:       kind,
-				Index:      i + 1,
-				Total:      totalVideos,
-				VideoTitle: video.Title,
-				VideoID:    video.ID,
-				Error:      downloadErr.Error(),
-			})
-			result.Errors = append(result.Errors, YouTubeImportError{
-				Title:   video.Title,
-				VideoID: video.ID,
-				Error:   downloadErr.Error(),
-			})
+		playlistInput := input
+		playlistInput.URL = url
+		playlistInput.seenVideoIDs = seenVideoIDs
+
+		result, err := s.ImportYouTube(ctx, ref, playlistInput, encryptionKey, playlistProgress)
+		if err != nil {
+			return results, fmt.Errorf("import playlist %d: %w", i+1, err)
+		}
+		for _, item := range result.Items {
+			seenVideoIDs[item.VideoID] = true
+		}
+		for _, item := range result.SkippedItems {
+			seenVideoIDs[item.VideoID] = true
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// ImportYouTubeURLList imports every URL listed in urlListReader, one per line. Blank lines and
+// lines starting with # are ignored. The URLs are imported like ImportYouTubePlaylists does, so
+// progress receives the index of the URL among the listed ones.
+func (s *BucketService) ImportYouTubeURLList(
+	ctx context.Context,
+	ref BucketRef,
+	urlListReader io.Reader,
+	input YouTubeImportInput,
+	encryptionKey []byte,
+	progress func(int, YouTubeImportProgress),
+) ([]*YouTubeImportResult, error) {
+	urls := make([]string, 0)
+	scanner := bufio.NewScanner(urlListReader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
+		urls = append(urls, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read url list: %w", err)
+	}
 
-		if skipped {
-			result.Skipped++
-			emitProgress(progress, YouTubeImportProgress{
-				Stage:      "skipped",
-				Kind:       kind,
-				Index:      i + 1,
-				Total:      totalVideos,
+	return s.ImportYouTubePlaylists(ctx, ref, urls, input, encryptionKey, progress)
+}
+
+// youtubeImportRun carries the state shared by all videos of a single ImportYouTube call.
+type youtubeImportRun struct {
+	bucket     BucketRef
+	sessionID  uuid.UUID
+	store      storage.ObjectStoreClient
+	bucketName string
+	prefix     string
+	client     YouTubeClient
+	kind       string
+	total      int
+	input      YouTubeImportInput
+	duplicates DuplicateChecker
+	progress   func(YouTubeImportProgress)
+
+	mu     sync.Mutex // guards result
+	result *YouTubeImportResult
+}
+
+// importYouTubeVideo downloads a single video of the run and records the outcome in run.result.
+func (s *BucketService) importYouTubeVideo(ctx context.Context, run *youtubeImportRun, index int, video *youtube.Video) {
+	var liveErr error
+	if run.input.WaitForLiveStream && isOngoingLiveStream(video) {
+		video, liveErr = s.waitForLiveStream(ctx, run, index, video)
+	}
+
+	var format *youtube.Format
+	formats, formatErr := s.formatSelector.SelectFormat(video, &run.input)
+	if formatErr == nil && len(formats) == 0 {
+		formatErr = errors.New("no downloadable formats were found")
+	}
+	if formatErr == nil {
+		format = formats[0]
+	}
+	separate := selectSeparateYouTubeFormats(video, run.input)
+	if separate != nil {
+		format, formatErr = separate.muxedFormat(), nil
+	}
+	if maxKbps := run.input.MaxBitrateKbps; maxKbps > 0 && format != nil && format.Bitrate > maxKbps*1000 {
+		s.logger.Warn("no youtube format within the bitrate limit, importing the lowest bitrate",
+			"video_id", video.ID,
+			"max_bitrate_kbps", maxKbps,
+			"bitrate_kbps", bitrateKbps(format),
+		)
+	}
+
+	starting := YouTubeImportProgress{
+		Stage:      StageStarting,
+		Kind:       run.kind,
+		Index:      index,
+		Total:      run.total,
+		VideoTitle: video.Title,
+		VideoID:    video.ID,
+		Author:     video.Author,
+		Message:    fmt.Sprintf("Downloading %q", video.Title),
+	}
+	if format != nil {
+		starting.Width = format.Width
+		starting.Height = format.Height
+		starting.BitrateKbps = bitrateKbps(format)
+	}
+	emitProgress(run.progress, starting)
+
+	progressFn := func(snapshot progressSnapshot) {
+		emitProgress(run.progress, YouTubeImportProgress{
+			Stage:                   StageDownloading,
+			Kind:                    run.kind,
+			Index:                   index,
+			Total:                   run.total,
+			VideoTitle:              video.Title,
+			VideoID:                 video.ID,
+			BytesRead:               snapshot.BytesRead,
+			TotalBytesExpected:      snapshot.Total,
+			Percent:                 computePercent(snapshot.BytesRead, snapshot.Total),
+			SpeedBytesPerSec:        snapshot.AvgSpeed,
+			InstantSpeedBytesPerSec: snapshot.InstantSpeed,
+			AvgSpeedBytesPerSec:     snapshot.AvgSpeed,
+		})
+		if snapshot.ReadCalls > 0 {
+			emitProgress(run.progress, YouTubeImportProgress{
+				Stage:      StageDebug,
+				Kind:       run.kind,
+				Index:      index,
+				Total:      run.total,
 				VideoTitle: video.Title,
 				VideoID:    video.ID,
-				Message:    fmt.Sprintf("%q already exists, skipping", video.Title),
-				Skipped:    true,
+				BytesRead:  snapshot.BytesRead,
+				ReadCalls:  snapshot.ReadCalls,
 			})
-			continue
 		}
+	}
+
+	var (
+		item        *YouTubeImportedItem
+		skipped     bool
+		downloadErr = cmp.Or(liveErr, formatErr)
+	)
+	if downloadErr == nil {
+		item, skipped, downloadErr = s.downloadYouTubeVideo(ctx, run, index, video, format, separate, progressFn)
+	}
+	if item != nil && !skipped && separate == nil && len(formats) > 1 {
+		item.AlternateFormats = formatInfos(formats[1:])
+	}
 
-		result.Items = append(result.Items, *item)
-		result.Imported++
-		result.TotalBytes += item.SizeBytes
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	result := run.result
+
+	if downloadErr != nil {
+		s.logger.Warn("failed to import youtube video",
+			"title", video.Title,
+			"video_id", video.ID,
+			"error", downloadErr,
+		)
+		emitProgress(run.progress, YouTubeImportProgress{
+			Stage:      StageError,
+			Kind:       run.kind,
+			Index:      index,
+			Total:      run.total,
+			VideoTitle: video.Title,
+			VideoID:    video.ID,
+			Error:      downloadErr.Error(),
+		})
+		importErr := YouTubeImportError{
+			Title:   video.Title,
+			VideoID: video.ID,
+			Error:   downloadErr.Error(),
+		}
+		if errors.Is(downloadErr, ErrDownloadStalled) {
+			importErr.ErrorKind = "stall"
+		}
+		result.Errors = append(result.Errors, importErr)
+		return
+	}
 
-		emitProgress(progress, YouTubeImportProgress{
-			Stage:       "downloaded",
-			Kind:        kind,
-			Index:       i + 1,
-			Total:       totalVideos,
-			VideoTitle:  video.Title,
-			VideoID:     video.ID,
-			Message:     fmt.Sprintf("Downloaded %q", video.Title),
-			Imported:    result.Imported,
-			Failed:      len(result.Errors),
-			TotalBytes:  result.TotalBytes,
-			Destination: item.Key,
+	if skipped {
+		result.Skipped++
+		if item != nil {
+			result.SkippedItems = append(result.SkippedItems, *item)
+		}
+		emitProgress(run.progress, YouTubeImportProgress{
+			Stage:      StageSkipped,
+			Kind:       run.kind,
+			Index:      index,
+			Total:      run.total,
+			VideoTitle: video.Title,
+			VideoID:    video.ID,
+			Message:    fmt.Sprintf("%q already exists, skipping", video.Title),
+			Skipped:    true,
 		})
+		return
 	}
 
-	if result.Imported > 0 {
-		go func() {
-			if err := s.recalculateBucketSize(context.Background(), bucketID, userID, encryptionKey); err != nil {
-				s.logger.Error("failed to recalculate bucket size after youtube import",
-					"bucket_id", bucketID.String(),
-					"error", err,
-				)
-			}
-		}()
+	result.Items = append(result.Items, *item)
+	result.Imported++
+	result.TotalBytes += item.SizeBytes
+
+	message := fmt.Sprintf("Downloaded %q", video.Title)
+	if run.input.DryRun {
+		message = fmt.Sprintf("Would download %q", video.Title)
 	}
 
-	emitProgress(progress, YouTubeImportProgress{
-		Stage:        "finished",
-		Kind:         kind,
-		Imported:     result.Imported,
-		Failed:       len(result.Errors),
-		SkippedCount: result.Skipped,
-		Total:        totalVideos,
-		TotalBytes:   result.TotalBytes,
-		Message:      "Import complete",
+	emitProgress(run.progress, YouTubeImportProgress{
+		Stage:       StageDownloaded,
+		Kind:        run.kind,
+		Index:       index,
+		Total:       run.total,
+		VideoTitle:  video.Title,
+		VideoID:     video.ID,
+		Author:      video.Author,
+		Message:     message,
+		Imported:    result.Imported,
+		Failed:      len(result.Errors),
+		TotalBytes:  result.TotalBytes,
+		Destination: item.Key,
+
+		PeakSpeedBytesPerSec: item.PeakDownloadSpeedBytesPerSec,
+		MinSpeedBytesPerSec:  item.MinDownloadSpeedBytesPerSec,
 	})
-
-	return result, nil
 }
 
 func (s *BucketService) resolveYouTubeVideos(
 	ctx context.Context,
-	client *youtube.Client,
+	client YouTubeClient,
 	url string,
 	result *YouTubeImportResult,
 	progress func(YouTubeImportProgress),
 ) ([]*youtube.Video, string, error) {
+	if isYouTubeMixURL(url) {
+		// Mix playlists are generated on the fly and cannot be fetched through the
+		// playlist endpoint, so fall back to the seed video referenced by the link.
+		video, err := client.GetVideoContext(ctx, url)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to load mix seed video: %w", err)
+		}
+		result.Kind = "mix"
+		return []*youtube.Video{video}, "mix", nil
+	}
+
 	playlist, err := client.GetPlaylistContext(ctx, url)
 	if err == nil {
 		result.Kind = "playlist"
@@ -264,189 +977,790 @@ func (s *BucketService) resolveYouTubeVideos(
 		return nil, "", fmt.Errorf("failed to load video: %w", videoErr)
 	}
 
-	return []*youtube.Video{video}, "video", nil
-}
+	return []*youtube.Video{video}, "video", nil
+}
+
+// isYouTubeMixURL reports whether the link points at an auto-generated Mix
+// (radio) playlist, whose list IDs start with "RD".
+func isYouTubeMixURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(parsed.Query().Get("list"), "RD")
+}
+
+func (s *BucketService) videosFromPlaylist(
+	ctx context.Context,
+	client YouTubeClient,
+	playlist *youtube.Playlist,
+	result *YouTubeImportResult,
+	progress func(YouTubeImportProgress),
+) []*youtube.Video {
+	videos := make([]*youtube.Video, 0, len(playlist.Videos))
+	for _, entry := range playlist.Videos {
+		video, err := client.VideoFromPlaylistEntryContext(ctx, entry)
+		if err != nil {
+			s.recordUnresolvedVideo("playlist", entry.Title, entry.ID, err, result, progress)
+			continue
+		}
+		videos = append(videos, video)
+	}
+	return videos
+}
+
+// recordUnresolvedVideo notes a video whose details could not be loaded, separating private
+// or removed videos from real failures.
+func (s *BucketService) recordUnresolvedVideo(
+	kind, title, videoID string,
+	err error,
+	result *YouTubeImportResult,
+	progress func(YouTubeImportProgress),
+) {
+	if isYouTubeUnavailableError(err) {
+		s.logger.Info("skipping unavailable "+kind+" entry", "video_id", videoID, "title", title, "error", err)
+		result.Unavailable = append(result.Unavailable, YouTubeImportError{
+			Title:   title,
+			VideoID: videoID,
+			Error:   err.Error(),
+		})
+		emitProgress(progress, YouTubeImportProgress{
+			Stage:      StageUnavailable,
+			Kind:       kind,
+			VideoTitle: title,
+			VideoID:    videoID,
+			Message:    fmt.Sprintf("%q is private or unavailable, skipping", cmp.Or(title, videoID)),
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	s.logger.Warn("failed to load "+kind+" entry", "video_id", videoID, "title", title, "error", err)
+	result.Errors = append(result.Errors, YouTubeImportError{
+		Title:   title,
+		VideoID: videoID,
+		Error:   err.Error(),
+	})
+	emitProgress(progress, YouTubeImportProgress{
+		Stage:      StageError,
+		Kind:       kind,
+		VideoTitle: title,
+		VideoID:    videoID,
+		Error:      err.Error(),
+	})
+}
+
+// isYouTubeUnavailableError reports whether err means the video exists but cannot
+// be downloaded (private, removed, age-restricted, ...) rather than a broken import.
+func isYouTubeUnavailableError(err error) bool {
+	if errors.Is(err, youtube.ErrVideoPrivate) || errors.Is(err, youtube.ErrLoginRequired) || errors.Is(err, youtube.ErrNotPlayableInEmbed) {
+		return true
+	}
+	var playability *youtube.ErrPlayabiltyStatus
+	return errors.As(err, &playability)
+}
+
+func filterYouTubeShorts(
+	videos []*youtube.Video,
+	kind string,
+	result *YouTubeImportResult,
+	progress func(YouTubeImportProgress),
+) []*youtube.Video {
+	filtered := make([]*youtube.Video, 0, len(videos))
+	for _, video := range videos {
+		if video.Duration > 0 && video.Duration < youtubeShortsMaxDuration {
+			result.ShortsSkipped++
+			emitProgress(progress, YouTubeImportProgress{
+				Stage:      StageSkipped,
+				Kind:       kind,
+				VideoTitle: video.Title,
+				VideoID:    video.ID,
+				Message:    "YouTube Short",
+				Skipped:    true,
+			})
+			continue
+		}
+		filtered = append(filtered, video)
+	}
+	return filtered
+}
+
+// filterYouTubeVideosSeen drops videos that another import of the same batch already handled
+func filterYouTubeVideosSeen(
+	videos []*youtube.Video,
+	seen map[string]bool,
+	kind string,
+	result *YouTubeImportResult,
+	progress func(YouTubeImportProgress),
+) []*youtube.Video {
+	filtered := make([]*youtube.Video, 0, len(videos))
+	for _, video := range videos {
+		if seen[video.ID] {
+			result.DuplicatesSkipped++
+			emitProgress(progress, YouTubeImportProgress{
+				Stage:      StageSkipped,
+				Kind:       kind,
+				VideoTitle: video.Title,
+				VideoID:    video.ID,
+				Message:    "Already imported from another playlist",
+				Skipped:    true,
+			})
+			continue
+		}
+		filtered = append(filtered, video)
+	}
+	return filtered
+}
+
+// filterYouTubeVideosPublishedBefore drops videos published at or before cutoff.
+// Videos without a known publish date are kept so they are never silently lost.
+func filterYouTubeVideosPublishedBefore(
+	videos []*youtube.Video,
+	cutoff time.Time,
+	kind string,
+	result *YouTubeImportResult,
+	progress func(YouTubeImportProgress),
+) []*youtube.Video {
+	filtered := make([]*youtube.Video, 0, len(videos))
+	for _, video := range videos {
+		if !video.PublishDate.IsZero() && !video.PublishDate.After(cutoff) {
+			result.OlderSkipped++
+			emitProgress(progress, YouTubeImportProgress{
+				Stage:      StageSkipped,
+				Kind:       kind,
+				VideoTitle: video.Title,
+				VideoID:    video.ID,
+				Message:    fmt.Sprintf("Published before %s", cutoff.Format(time.RFC3339)),
+				Skipped:    true,
+			})
+			continue
+		}
+		filtered = append(filtered, video)
+	}
+	return filtered
+}
+
+func (s *BucketService) downloadYouTubeVideo(
+	ctx context.Context,
+	run *youtubeImportRun,
+	index int,
+	video *youtube.Video,
+	format *youtube.Format,
+	separate *youtubeStreamPair,
+	progress func(progressSnapshot),
+) (*YouTubeImportedItem, bool, error) {
+	store, bucketName, prefix, client, input := run.store, run.bucketName, run.prefix, run.client, run.input
+
+	// A stalled download cancels this context so the blocked stream and upload both unwind.
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// format describes the stored file from here on; the stream is still read in sourceFormat
+	sourceFormat := format
+	burnSubtitles := input.BurnSubtitles && !input.AudioOnly
+	if burnSubtitles {
+		format = burnedSubtitleFormat(format)
+	}
+
+	// The stream is opened lazily so that skipped videos and dry runs never start a download.
+	var stream io.ReadCloser
+	openSourceStream := func() error {
+		if separate != nil {
+			rc, err := s.openSeparateYouTubeStreams(ctx, client, video, separate)
+			if err != nil {
+				return err
+			}
+			stream = rc
+			return nil
+		}
+		rc, sizeHint, err := client.GetStreamContext(ctx, video, sourceFormat)
+		if err != nil {
+			return err
+		}
+		stream = rc
+		if sourceFormat.ContentLength == 0 && sizeHint > 0 {
+			sourceFormat.ContentLength = sizeHint
+		}
+		return nil
+	}
+	openStream := func() error {
+		if stream != nil {
+			stream.Close()
+			stream = nil
+		}
+		if !burnSubtitles {
+			return openSourceStream()
+		}
+
+		emitProgress(run.progress, YouTubeImportProgress{
+			Stage:      StageCaptions,
+			Kind:       run.kind,
+			Index:      index,
+			Total:      run.total,
+			VideoTitle: video.Title,
+			VideoID:    video.ID,
+			Message:    fmt.Sprintf("Downloading subtitles of %q", video.Title),
+		})
+		subtitlePath, err := downloadYouTubeSubtitles(ctx, client, video, input)
+		if err != nil {
+			return err
+		}
+		if err := openSourceStream(); err != nil {
+			os.Remove(subtitlePath)
+			return err
+		}
+
+		emitProgress(run.progress, YouTubeImportProgress{
+			Stage:      StageMuxing,
+			Kind:       run.kind,
+			Index:      index,
+			Total:      run.total,
+			VideoTitle: video.Title,
+			VideoID:    video.ID,
+			Message:    fmt.Sprintf("Burning subtitles into %q", video.Title),
+		})
+		burned, err := s.burnYouTubeSubtitles(ctx, stream, subtitlePath)
+		if err != nil {
+			stream.Close()
+			stream = nil
+			return err
+		}
+		stream = burned
+		return nil
+	}
+	defer func() {
+		if stream != nil {
+			stream.Close()
+		}
+	}()
+
+	contentType := s.contentTypeFromMime(format.MimeType)
+	primaryFilename := s.fileNames.BuildFileName(video, format, index, input)
+	primaryKey := primaryFilename
+	if prefix != "" {
+		primaryKey = prefix + primaryFilename
+	}
+
+	legacyKey := withVideoIDSuffix(primaryKey, video.ID)
+
+	newItem := func(key string, size int64) *YouTubeImportedItem {
+		return &YouTubeImportedItem{
+			Title:           video.Title,
+			Key:             key,
+			VideoID:         video.ID,
+			SizeBytes:       size,
+			ContentType:     contentType,
+			Width:           format.Width,
+			Height:          format.Height,
+			BitrateKbps:     bitrateKbps(format),
+			PublishedAt:     video.PublishDate,
+			DurationSeconds: int(video.Duration / time.Second),
+			Author:          video.Author,
+		}
+	}
 
-func (s *BucketService) videosFromPlaylist(
-	ctx context.Context,
-	client *youtube.Client,
-	playlist *youtube.Playlist,
-	result *YouTubeImportResult,
-	progress func(YouTubeImportProgress),
-) []*youtube.Video {
-	videos := make([]*youtube.Video, 0, len(playlist.Videos))
-	for _, entry := range playlist.Videos {
-		video, err := client.VideoFromPlaylistEntryContext(ctx, entry)
+	// existingIsIntact decides whether an already imported object can be kept. With checksum
+	// verification enabled, the checksum recorded on the object is compared with a fresh
+	// download; the fresh stream is consumed by the comparison and reopened for re-import.
+	// The size of an object that is going to be replaced is returned with the decision.
+	existingIsIntact := func(key string) (bool, int64, error) {
+		if input.OverwritePolicy != OverwriteReplaceExisting && (!input.VerifyChecksum || input.DryRun) {
+			return true, 0, nil
+		}
+		head, err := store.HeadObject(ctx, bucketName, key)
 		if err != nil {
-			s.logger.Warn("failed to load playlist entry", "video_id", entry.ID, "title", entry.Title, "error", err)
-			result.Errors = append(result.Errors, YouTubeImportError{
-				Title:   entry.Title,
-				VideoID: entry.ID,
-				Error:   err.Error(),
-			})
-			emitProgress(progress, YouTubeImportProgress{
-				Stage:      "error",
-				Kind:       "playlist",
-				VideoTitle: entry.Title,
-				VideoID:    entry.ID,
-				Error:      err.Error(),
-			})
-			continue
+			return false, 0, err
+		}
+		size := awsInt64Value(head.ContentLength)
+		if input.OverwritePolicy == OverwriteReplaceExisting {
+			return false, size, nil
+		}
+		expected := metadataValue(head.Metadata, s.metadataKey(youtubeSHA256MetadataKey))
+		if expected == "" {
+			return true, size, nil
 		}
-		videos = append(videos, video)
-	}
-	return videos
-}
 
-func (s *BucketService) downloadYouTubeVideo(
-	ctx context.Context,
-	store *storage.ObjectStore,
-	bucketName string,
-	prefix string,
-	client *youtube.Client,
-	video *youtube.Video,
-	progress func(int64, int64, float64),
-) (*YouTubeImportedItem, bool, error) {
-	format, err := selectYouTubeFormat(video)
-	if err != nil {
-		return nil, false, err
+		if err := openStream(); err != nil {
+			return false, 0, err
+		}
+		digest := sha256.New()
+		if _, err := io.Copy(digest, stream); err != nil {
+			return false, 0, err
+		}
+		if hex.EncodeToString(digest.Sum(nil)) == expected {
+			return true, size, nil
+		}
+
+		s.logger.Warn("checksum mismatch for imported youtube video, re-importing",
+			"key", key,
+			"video_id", video.ID,
+		)
+		stream.Close()
+		stream = nil
+		return false, size, nil
 	}
 
-	stream, sizeHint, err := client.GetStreamContext(ctx, video, format)
+	// The duplicate checks below can take a while on high-latency endpoints
+	emitProgress(run.progress, YouTubeImportProgress{
+		Stage:      StageChecking,
+		Kind:       run.kind,
+		Index:      index,
+		Total:      run.total,
+		VideoTitle: video.Title,
+		VideoID:    video.ID,
+		Message:    fmt.Sprintf("Checking whether %q was already imported", video.Title),
+	})
+
+	key := primaryKey
+	replacedSize := int64(0)
+
+	duplicate, existingKey, err := run.duplicates.IsDuplicate(ctx, store, bucketName, primaryKey, video)
 	if err != nil {
 		return nil, false, err
 	}
-	defer stream.Close()
-
-	if format.ContentLength == 0 && sizeHint > 0 {
-		format.ContentLength = sizeHint
+	if duplicate {
+		intact, size, verifyErr := existingIsIntact(existingKey)
+		if verifyErr != nil {
+			return nil, false, verifyErr
+		}
+		if intact {
+			return newItem(existingKey, 0), true, nil
+		}
+		key = existingKey
+		replacedSize = size
+	} else {
+		// Not a duplicate, but the name may still be taken
+		primaryHead, err := store.HeadObject(ctx, bucketName, primaryKey)
+		switch {
+		case err != nil && !isNotFoundError(err):
+			return nil, false, err
+		case err != nil:
+			// The name is free
+		case metadataMatchesYouTubeVideo(primaryHead.Metadata, s.metadataKey(youtubeVideoIDMetadataKey), video.ID):
+			// An earlier import of the video that the checker chose not to keep
+			replacedSize = awsInt64Value(primaryHead.ContentLength)
+		case input.OverwriteOnTitleMatch:
+			s.logger.Warn("overwriting existing object with the same name as imported youtube video",
+				"key", primaryKey,
+				"video_id", video.ID,
+				"existing_metadata", primaryHead.Metadata,
+				"existing_size", awsInt64Value(primaryHead.ContentLength),
+			)
+			replacedSize = awsInt64Value(primaryHead.ContentLength)
+		default:
+			// A file already exists with the desired title, fall back to the legacy naming that
+			// includes the video ID to avoid overwriting unrelated content.
+			key = legacyKey
+		}
 	}
 
-	contentType := contentTypeFromMime(format.MimeType)
-	primaryFilename := buildYouTubeFilename(video.Title, format)
-	primaryKey := primaryFilename
-	if prefix != "" {
-		primaryKey = prefix + primaryFilename
+	if input.DryRun {
+		return newItem(key, format.ContentLength), false, nil
 	}
 
-	legacyFilename := buildYouTubeFilenameWithID(video.Title, video.ID, format)
-	legacyKey := legacyFilename
-	if prefix != "" {
-		legacyKey = prefix + legacyFilename
+	if stream == nil {
+		if err := openStream(); err != nil {
+			return nil, false, err
+		}
 	}
 
-	primaryHead, err := store.HeadObject(ctx, bucketName, primaryKey)
-	if err != nil && !isNotFoundError(err) {
-		return nil, false, err
+	importedAt := time.Now().UTC()
+	metadata := map[string]string{
+		s.metadataKey(youtubeVideoIDMetadataKey):    video.ID,
+		s.metadataKey(youtubeImportedByMetadataKey): run.bucket.UserID.String(),
+		s.metadataKey(youtubeImportedAtMetadataKey): importedAt.Format(time.RFC3339),
+		s.metadataKey(youtubeSessionIDMetadataKey):  run.sessionID.String(),
 	}
-	if err == nil && metadataMatchesYouTubeVideo(primaryHead.Metadata, video.ID) {
-		return &YouTubeImportedItem{
-			Title:       video.Title,
-			Key:         primaryKey,
-			VideoID:     video.ID,
-			SizeBytes:   0,
-			ContentType: contentType,
-		}, true, nil
+	if video.Title != "" {
+		metadata[s.metadataKey(youtubeVideoTitleMetadataKey)] = video.Title
+	}
+	if video.Author != "" {
+		metadata[s.metadataKey(youtubeChannelMetadataKey)] = video.Author
 	}
-	if err != nil && isNotFoundError(err) {
-		primaryHead = nil
+	if input.ImportNote != "" {
+		metadata[s.metadataKey(youtubeImportNoteMetadataKey)] = input.ImportNote
+	}
+	if format.Width > 0 && format.Height > 0 {
+		metadata[s.metadataKey(youtubeResolutionMetadataKey)] = fmt.Sprintf("%dx%d", format.Width, format.Height)
+	}
+	if video.Duration > 0 {
+		metadata[s.metadataKey(youtubeDurationMetadataKey)] = strconv.Itoa(int(video.Duration / time.Second))
+	}
+	var viewCount, likeCount int64
+	if input.StoreVideoStats {
+		viewCount = int64(video.Views)
+		metadata[s.metadataKey(youtubeViewCountMetadataKey)] = strconv.FormatInt(viewCount, 10)
+		if s.youtubeAPIKey != "" {
+			count, ok, err := s.youtubeVideoLikeCount(ctx, video.ID)
+			if err != nil {
+				s.logger.Warn("failed to load youtube like count", "video_id", video.ID, "error", err)
+			} else if ok {
+				likeCount = count
+				metadata[s.metadataKey(youtubeLikeCountMetadataKey)] = strconv.FormatInt(likeCount, 10)
+			}
+		}
 	}
 
-	if _, err := store.HeadObject(ctx, bucketName, legacyKey); err == nil {
-		return &YouTubeImportedItem{
-			Title:       video.Title,
-			Key:         legacyKey,
-			VideoID:     video.ID,
-			SizeBytes:   0,
-			ContentType: contentType,
-		}, true, nil
-	} else if !isNotFoundError(err) {
-		return nil, false, err
+	readerOpts := []progressReaderOption{WithBufferSize(defaultImportBufferSize)}
+	if input.Debug {
+		readerOpts = append(readerOpts, WithDebug())
+	}
+	if input.VerifyChecksum {
+		readerOpts = append(readerOpts, WithHasher(sha256.New()))
 	}
+	if input.StallTimeoutSeconds > 0 {
+		stalledStream := stream
+		readerOpts = append(readerOpts, WithStallTimeout(time.Duration(input.StallTimeoutSeconds)*time.Second, func() {
+			cancel(ErrDownloadStalled)
+			stalledStream.Close()
+		}))
+	}
+	progressReader := newProgressReader(stream, format.ContentLength, progress, readerOpts...)
+	defer progressReader.Close()
 
-	key := primaryKey
-	if primaryHead != nil {
-		// A file already exists with the desired title, fall back to the legacy naming that
-		// includes the video ID to avoid overwriting unrelated content.
-		key = legacyKey
+	var body io.Reader = progressReader
+	if input.BandwidthLimit > 0 {
+		body = newThrottledReader(ctx, body, input.BandwidthLimit)
 	}
 
-	metadata := map[string]string{
-		youtubeVideoIDMetadataKey: video.ID,
+	var objectOpts []storage.ObjectOption
+	if input.ContentDisposition != "" {
+		objectOpts = append(objectOpts, storage.WithContentDisposition(contentDisposition(input.ContentDisposition, video.Title, key)))
 	}
-	if video.Title != "" {
-		metadata[youtubeVideoTitleMetadataKey] = video.Title
+	if input.CacheControl != "" {
+		objectOpts = append(objectOpts, storage.WithCacheControl(input.CacheControl))
+	}
+	if input.ServerSideEncryption != "" {
+		objectOpts = append(objectOpts, storage.WithServerSideEncryption(input.ServerSideEncryption, input.SSEKMSKeyID))
+	}
+	if input.ObjectLockEnabled {
+		objectOpts = append(objectOpts, storage.WithObjectLock(input.ObjectLockMode, input.ObjectLockRetainUntil))
+	}
+	partSize := int64(cmp.Or(input.MultipartChunkSizeMB, defaultMultipartChunkSizeMB)) * 1024 * 1024
+	totalParts := 0
+	if format.ContentLength > 0 {
+		totalParts = int((format.ContentLength + partSize - 1) / partSize)
 	}
+	objectOpts = append(objectOpts, storage.WithMultipartUpload(partSize, func(partNumber int) {
+		emitProgress(run.progress, YouTubeImportProgress{
+			Stage:      StageUploading,
+			Kind:       run.kind,
+			Index:      index,
+			Total:      run.total,
+			VideoTitle: video.Title,
+			VideoID:    video.ID,
+			PartNumber: partNumber,
+			TotalParts: max(totalParts, partNumber),
+		})
+	}))
 
-	progressReader := newProgressReader(stream, format.ContentLength, progress)
-	defer progressReader.Close()
+	// The checksum is added after the upload, so its room is reserved now
+	reserved := 0
+	if input.VerifyChecksum {
+		reserved = len(s.metadataKey(youtubeSHA256MetadataKey)) + hex.EncodedLen(sha256.Size)
+	}
+	if err := checkUserMetadataSize(metadata, reserved); err != nil {
+		return nil, false, err
+	}
 
-	if err := store.PutObject(ctx, bucketName, key, progressReader, contentType, metadata); err != nil {
+	etag, err := store.PutObject(ctx, bucketName, key, body, contentType, metadata, objectOpts...)
+	if err != nil {
+		if errors.Is(context.Cause(ctx), ErrDownloadStalled) {
+			return nil, false, fmt.Errorf("%w after %ds without data", ErrDownloadStalled, input.StallTimeoutSeconds)
+		}
+		if storage.IsStorageFull(err) {
+			return nil, false, s.quotaExceededError(ctx, run.bucket)
+		}
 		return nil, false, err
 	}
 
+	if input.VerifyChecksum {
+		metadata[s.metadataKey(youtubeSHA256MetadataKey)] = hex.EncodeToString(progressReader.Sum())
+		if err := store.ReplaceObjectMetadata(ctx, bucketName, key, contentType, metadata, objectOpts...); err != nil {
+			return nil, false, fmt.Errorf("store checksum: %w", err)
+		}
+	}
+
+	if len(input.Tags) > 0 {
+		if err := store.PutObjectTagging(ctx, bucketName, key, input.Tags); err != nil {
+			return nil, false, fmt.Errorf("tag object: %w", err)
+		}
+	}
+
 	size := progressReader.BytesRead()
 	if size == 0 && format.ContentLength > 0 {
 		size = format.ContentLength
 	}
 
-	return &YouTubeImportedItem{
-		Title:       video.Title,
-		Key:         key,
-		VideoID:     video.ID,
-		SizeBytes:   size,
-		ContentType: contentType,
-	}, false, nil
+	// Keep the stored bucket size current while the import is running; the full recalculation
+	// after the import corrects any drift
+	if err := s.recalculateBucketSizeDelta(ctx, run.bucket, size-replacedSize); err != nil {
+		s.logger.Warn("failed to update bucket size after youtube upload",
+			"key", key,
+			"bucket_id", run.bucket.BucketID.String(),
+			"error", err,
+		)
+	}
+
+	item := newItem(key, size)
+	item.ETag = etag
+	item.ImportedBy = run.bucket.UserID.String()
+	item.ImportedAt = importedAt.Truncate(time.Second)
+	item.ViewCount = viewCount
+	item.LikeCount = likeCount
+	item.PeakDownloadSpeedBytesPerSec = progressReader.PeakSpeed()
+	item.MinDownloadSpeedBytesPerSec = progressReader.MinSpeed()
+	return item, false, nil
+}
+
+// FormatSelector ranks the formats an import may download for a video, best first. The first
+// one is downloaded; the others are recorded as the item's AlternateFormats. Install a custom
+// one with WithFormatSelector, e.g. to always download a specific itag or to reorder the
+// ranking of DefaultFormatSelector.
+type FormatSelector interface {
+	SelectFormat(video *youtube.Video, input *YouTubeImportInput) ([]*youtube.Format, error)
+}
+
+// DefaultFormatSelector prefers the requested quality and otherwise the best format with audio,
+// or the best audio-only format for AudioOnly imports
+type DefaultFormatSelector struct{}
+
+func (DefaultFormatSelector) SelectFormat(video *youtube.Video, input *YouTubeImportInput) ([]*youtube.Format, error) {
+	return selectYouTubeFormat(video, *input)
 }
 
-func selectYouTubeFormat(video *youtube.Video) (*youtube.Format, error) {
+// selectYouTubeFormat returns the candidate formats in order of preference: MP4 before other
+// containers, except that AudioOnly imports rank Opus first for its better quality at the same
+// bitrate, then by the order of youtube.FormatList.Sort
+func selectYouTubeFormat(video *youtube.Video, input YouTubeImportInput) ([]*youtube.Format, error) {
 	withAudio := video.Formats.WithAudioChannels()
 	if len(withAudio) == 0 {
 		return nil, fmt.Errorf("no downloadable formats with audio were found")
 	}
 
-	var mp4Formats youtube.FormatList
+	if input.AudioOnly {
+		audioOnly := withAudio.Select(func(format youtube.Format) bool {
+			return strings.HasPrefix(format.MimeType, "audio/")
+		})
+		if len(audioOnly) == 0 {
+			return nil, fmt.Errorf("no audio-only formats were found")
+		}
+		withAudio = audioOnly
+	}
+
+	if quality := strings.TrimSpace(input.Quality); quality != "" {
+		matching := withAudio.Select(func(format youtube.Format) bool {
+			return strings.EqualFold(format.QualityLabel, quality) || strings.EqualFold(format.Quality, quality)
+		})
+		if len(matching) > 0 {
+			withAudio = matching
+		}
+	}
+
+	if input.MaxBitrateKbps > 0 {
+		withinLimit := withAudio.Select(func(format youtube.Format) bool {
+			return format.Bitrate <= input.MaxBitrateKbps*1000
+		})
+		if len(withinLimit) == 0 {
+			slices.SortStableFunc(withAudio, func(a, b youtube.Format) int {
+				return cmp.Compare(a.Bitrate, b.Bitrate)
+			})
+			return formatPointers(withAudio), nil
+		}
+		withAudio = withinLimit
+	}
+
+	var opusFormats, mp4Formats, otherFormats youtube.FormatList
 	for _, format := range withAudio {
-		if strings.Contains(format.MimeType, "mp4") {
+		switch {
+		case input.AudioOnly && isOpusFormat(format):
+			opusFormats = append(opusFormats, format)
+		case strings.Contains(format.MimeType, "mp4"):
 			mp4Formats = append(mp4Formats, format)
+		default:
+			otherFormats = append(otherFormats, format)
 		}
 	}
+	ranked := make(youtube.FormatList, 0, len(withAudio))
+	for _, formats := range []youtube.FormatList{opusFormats, mp4Formats, otherFormats} {
+		formats.Sort()
+		sortByHDR(formats, input.PreferHDR)
+		ranked = append(ranked, formats...)
+	}
 
-	candidate := withAudio
-	if len(mp4Formats) > 0 {
-		mp4Formats.Sort()
-		candidate = mp4Formats
-	} else {
-		candidate.Sort()
+	return formatPointers(ranked), nil
+}
+
+// isOpusFormat reports whether the format's codecs, e.g. `audio/webm; codecs="opus"`, include Opus
+func isOpusFormat(format youtube.Format) bool {
+	_, params, _ := strings.Cut(format.MimeType, ";")
+	return strings.Contains(strings.ToLower(params), "opus")
+}
+
+func formatPointers(formats youtube.FormatList) []*youtube.Format {
+	pointers := make([]*youtube.Format, len(formats))
+	for i := range formats {
+		pointers[i] = &formats[i]
+	}
+	return pointers
+}
+
+// FormatInfo describes a format of a video
+type FormatInfo struct {
+	QualityLabel string `json:"qualityLabel,omitempty"`
+	MimeType     string `json:"mimeType"`
+	Bitrate      int    `json:"bitrate,omitempty"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+}
+
+func formatInfos(formats []*youtube.Format) []FormatInfo {
+	infos := make([]FormatInfo, len(formats))
+	for i, format := range formats {
+		infos[i] = FormatInfo{
+			QualityLabel: format.QualityLabel,
+			MimeType:     format.MimeType,
+			Bitrate:      format.Bitrate,
+			Width:        format.Width,
+			Height:       format.Height,
+		}
+	}
+	return infos
+}
+
+// isHDRFormat reports whether YouTube labels the format as HDR, e.g. "2160p60 HDR"
+func isHDRFormat(format youtube.Format) bool {
+	return strings.Contains(strings.ToUpper(format.QualityLabel), "HDR")
+}
+
+// sortByHDR moves the HDR formats of a sorted list to the front when preferred and to the back
+// otherwise, keeping the order within each group
+func sortByHDR(formats youtube.FormatList, preferHDR bool) {
+	slices.SortStableFunc(formats, func(a, b youtube.Format) int {
+		aHDR, bHDR := isHDRFormat(a), isHDRFormat(b)
+		switch {
+		case aHDR == bHDR:
+			return 0
+		case aHDR == preferHDR:
+			return -1
+		default:
+			return 1
+		}
+	})
+}
+
+func bitrateKbps(format *youtube.Format) int {
+	if format == nil || format.Bitrate <= 0 {
+		return 0
 	}
+	return format.Bitrate / 1000
+}
 
-	selected := candidate[0]
-	return &selected, nil
+// FileNameStrategy names the object an imported video is stored as, relative to the import
+// prefix. Names may contain slashes to place videos in sub-folders. Install a custom one with
+// WithFileNameStrategy.
+type FileNameStrategy interface {
+	BuildFileName(video *youtube.Video, format *youtube.Format, index int, input YouTubeImportInput) string
 }
 
-func buildYouTubeFilename(title string, format *youtube.Format) string {
-	name := buildYouTubeBaseName(title)
+// DefaultFileNameStrategy names videos after their sanitized title and the format's extension
+type DefaultFileNameStrategy struct{}
+
+func (DefaultFileNameStrategy) BuildFileName(video *youtube.Video, format *youtube.Format, index int, input YouTubeImportInput) string {
+	return buildYouTubeFilename(video.Title, format, input)
+}
+
+func buildYouTubeFilename(title string, format *youtube.Format, input YouTubeImportInput) string {
+	name := buildYouTubeBaseName(title, input)
 	return fmt.Sprintf("%s%s", name, extensionFromMime(format.MimeType))
 }
 
-func buildYouTubeFilenameWithID(title, videoID string, format *youtube.Format) string {
-	name := buildYouTubeBaseName(title)
-	return fmt.Sprintf("%s-%s%s", name, videoID, extensionFromMime(format.MimeType))
+// withVideoIDSuffix inserts the video ID before the extension of key. Imports fall back to this
+// name when an unrelated object already uses the primary one.
+func withVideoIDSuffix(key, videoID string) string {
+	ext := path.Ext(key)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(key, ext), videoID, ext)
 }
 
-func buildYouTubeBaseName(title string) string {
-	name := sanitizeFileName(title)
+func buildYouTubeBaseName(title string, input YouTubeImportInput) string {
+	name := sanitizeFileName(title, input)
 	if name == "" {
 		name = "youtube-video"
 	}
-	if len(name) > 80 {
-		name = name[:80]
+	maxLength := input.MaxFilenameLengthBytes
+	if maxLength <= 0 {
+		maxLength = defaultMaxFilenameLengthBytes
+	}
+	return truncateUTF8(name, maxLength)
+}
+
+// truncateUTF8 shortens value to at most maxBytes bytes without splitting a multi-byte rune
+func truncateUTF8(value string, maxBytes int) string {
+	if len(value) <= maxBytes {
+		return value
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return value[:cut]
+}
+
+// DefaultCacheControl suggests a Cache-Control header for an object of the given content type.
+// Imported media never changes under its key, so it may be cached for a year; text such as
+// subtitles and metadata is refreshed more often.
+func DefaultCacheControl(contentType string) string {
+	mediaType, _, _ := strings.Cut(strings.ToLower(contentType), ";")
+	mediaType = strings.TrimSpace(mediaType)
+	switch {
+	case strings.HasPrefix(mediaType, "video/"), strings.HasPrefix(mediaType, "audio/"), strings.HasPrefix(mediaType, "image/"):
+		return "public, max-age=31536000, immutable"
+	case strings.HasPrefix(mediaType, "text/"), mediaType == "application/json", mediaType == "application/xml":
+		return "public, max-age=3600"
+	default:
+		return "public, max-age=86400"
 	}
-	return name
 }
 
-func sanitizeFileName(value string) string {
-	value = fileNameSanitizer.ReplaceAllString(value, "")
+// contentDisposition fills in the placeholders of a ContentDisposition template. The values are
+// reduced to printable ASCII without quotes so they can't break out of a quoted filename.
+func contentDisposition(template, title, key string) string {
+	return strings.NewReplacer(
+		"{title}", headerSafe(title),
+		"{filename}", headerSafe(path.Base(key)),
+	).Replace(template)
+}
+
+func headerSafe(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '"' || r == '\\':
+			return '\''
+		case r < 0x20 || r == 0x7f:
+			return -1
+		case r > 0x7e:
+			return '_'
+		}
+		return r
+	}, value)
+}
+
+func sanitizeFileName(value string, input YouTubeImportInput) string {
+	replacement := ""
+	if input.FilenameReplacementChar != 0 {
+		replacement = string(input.FilenameReplacementChar)
+	}
+	// Composed and decomposed forms of the same title must produce the same name
+	value = norm.NFC.String(value)
+	value = stripFormatCharacters(value)
+	if input.SanitizeMode == SanitizeModeUnicode {
+		value = unicodeFileNameSanitizer.ReplaceAllString(value, replacement)
+	} else {
+		value = fileNameSanitizer.ReplaceAllString(value, replacement)
+	}
+	if replacement != "" {
+		value = strings.Trim(value, replacement)
+	}
 	value = strings.TrimSpace(value)
 	value = strings.ReplaceAll(value, "/", "-")
 	value = strings.ReplaceAll(value, "\\", "-")
@@ -454,60 +1768,166 @@ func sanitizeFileName(value string) string {
 	return value
 }
 
-func metadataMatchesYouTubeVideo(metadata map[string]string, videoID string) bool {
+// stripFormatCharacters removes invisible format characters (Unicode category Cf) such as
+// zero-width spaces, soft hyphens and directional marks, which some titles contain
+func stripFormatCharacters(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+		return r
+	}, value)
+}
+
+func metadataMatchesYouTubeVideo(metadata map[string]string, videoIDKey, videoID string) bool {
 	if len(metadata) == 0 || videoID == "" {
 		return false
 	}
 	for key, value := range metadata {
-		if strings.EqualFold(key, youtubeVideoIDMetadataKey) && value == videoID {
+		if strings.EqualFold(key, videoIDKey) && value == videoID {
 			return true
 		}
 	}
 	return false
 }
 
+// checkUserMetadataSize returns ErrMetadataTooLarge when metadata plus reserved bytes would
+// exceed maxUserMetadataBytes
+func checkUserMetadataSize(metadata map[string]string, reserved int) error {
+	size := reserved
+	for key, value := range metadata {
+		size += len(key) + len(value)
+	}
+	if size > maxUserMetadataBytes {
+		return fmt.Errorf("%w: %d bytes", ErrMetadataTooLarge, size)
+	}
+	return nil
+}
+
+// metadataValue looks up a user metadata key case-insensitively, since S3
+// backends differ in how they normalise header names.
+func metadataValue(metadata map[string]string, key string) string {
+	for k, v := range metadata {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
 func extensionFromMime(mimeType string) string {
 	switch {
 	case strings.Contains(mimeType, "audio/mp4"):
 		return ".m4a"
+	case strings.Contains(mimeType, "audio/webm"):
+		return ".weba"
+	case strings.Contains(mimeType, "video/ogg"):
+		return ".ogv"
+	case strings.Contains(mimeType, "audio/ogg"):
+		return ".oga"
 	case strings.Contains(mimeType, "mp4"):
 		return ".mp4"
 	case strings.Contains(mimeType, "webm"):
 		return ".webm"
+	case strings.Contains(mimeType, "matroska"):
+		return ".mkv"
 	default:
 		return ".bin"
 	}
 }
 
-func contentTypeFromMime(mimeType string) string {
-	if idx := strings.Index(mimeType, ";"); idx > -1 {
-		mimeType = mimeType[:idx]
+// allowedImportContentTypes are the content types imported objects may be stored with
+var allowedImportContentTypes = map[string]bool{
+	"video/mp4":        true,
+	"video/webm":       true,
+	"video/ogg":        true,
+	"video/x-matroska": true,
+	"audio/mp4":        true,
+	"audio/webm":       true,
+	"audio/ogg":        true,
+	"audio/mpeg":       true,
+	"image/jpeg":       true,
+}
+
+// contentTypeFromMime strips the codec parameters from a YouTube MIME type. Types outside of
+// allowedImportContentTypes are stored as application/octet-stream rather than trusting
+// whatever the API reported.
+func (s *BucketService) contentTypeFromMime(mimeType string) string {
+	contentType := mimeType
+	if idx := strings.Index(contentType, ";"); idx > -1 {
+		contentType = contentType[:idx]
+	}
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	if !allowedImportContentTypes[contentType] {
+		s.logger.Warn("unexpected youtube content type, storing as binary", "mime_type", mimeType)
+		return "application/octet-stream"
 	}
-	return strings.TrimSpace(mimeType)
+	return contentType
 }
 
-func normalizeObjectPrefix(prefix string) string {
+// normalizeObjectPrefix cleans up a user supplied key prefix and rejects ".." segments so the
+// prefix cannot be used to address keys outside of the intended location
+func normalizeObjectPrefix(prefix string) (string, error) {
+	// Accept Windows-style paths such as Videos\YouTube\2024
+	prefix = strings.ReplaceAll(prefix, "\\", "/")
 	prefix = strings.TrimSpace(prefix)
 	prefix = strings.TrimPrefix(prefix, "/")
-	prefix = strings.ReplaceAll(prefix, "//", "/")
+	for strings.Contains(prefix, "//") {
+		prefix = strings.ReplaceAll(prefix, "//", "/")
+	}
 	prefix = strings.Trim(prefix, "/")
 	if prefix == "" {
-		return ""
+		return "", nil
+	}
+	for _, segment := range strings.Split(prefix, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("%w: %q", ErrInvalidPrefix, prefix)
+		}
 	}
-	return prefix + "/"
+	return prefix + "/", nil
 }
 
 func emitProgress(progress func(YouTubeImportProgress), event YouTubeImportProgress) {
 	if progress == nil {
 		return
 	}
+	event.Timestamp = time.Now()
 	progress(event)
 }
 
+// emitProgressAsync sends event on ch unless the channel is full, in which case the event is
+// dropped
+func (s *BucketService) emitProgressAsync(ch chan<- YouTubeImportProgress, event YouTubeImportProgress) {
+	select {
+	case ch <- event:
+	default:
+		s.logger.Warn("youtube import progress channel is full, dropping event",
+			"stage", string(event.Stage),
+			"video_id", event.VideoID,
+		)
+	}
+}
+
+// NotFoundError is implemented by storage backends that can report missing objects directly.
+type NotFoundError interface {
+	IsNotFound() bool
+}
+
 func isNotFoundError(err error) bool {
 	if err == nil {
 		return false
 	}
+
+	var notFound NotFoundError
+	if errors.As(err, &notFound) {
+		return notFound.IsNotFound()
+	}
+	if storage.IsNotFound(err) {
+		return true
+	}
+
+	// Fallback for S3-compatible backends whose errors are not mapped onto the SDK's
+	// typed errors; they still carry the error code or status in the message.
 	msg := strings.ToLower(err.Error())
 	return strings.Contains(msg, "notfound") || strings.Contains(msg, "status code: 404")
 }
@@ -519,40 +1939,211 @@ func computePercent(read, total int64) float64 {
 	return (float64(read) / float64(total)) * 100
 }
 
+// speedSmoothingFactor is the weight of the latest sample in the moving average download speed.
+const speedSmoothingFactor = 0.3
+
+// progressSnapshot is the state progressReader reports to its callback.
+type progressSnapshot struct {
+	BytesRead    int64
+	Total        int64
+	InstantSpeed float64
+	AvgSpeed     float64
+	// ReadCalls is only set when the reader has Debug enabled
+	ReadCalls int64
+}
+
 type progressReader struct {
 	rc        io.ReadCloser
 	total     int64
 	read      int64
 	lastBytes int64
 	lastTime  time.Time
-	callback  func(int64, int64, float64)
+	ewmaSpeed float64
+	hasSpeed  bool
+	// PeakSpeedBytesPerSec and MinSpeedBytesPerSec are the extremes of the per-report speed
+	PeakSpeedBytesPerSec float64
+	MinSpeedBytesPerSec  float64
+	finished             bool
+	callback             func(progressSnapshot)
+	BufferSizeBytes      int
+	hasher               hash.Hash
+	readCalls            atomic.Int64
+
+	// Debug adds the number of reads from the underlying stream to the reports, to compare
+	// buffer sizes
+	Debug bool
+
+	stallTimeout time.Duration
+	onStall      func()
+	lastActivity atomic.Int64 // unix nanoseconds of the last read that returned data
+	stopWatch    chan struct{}
+	stopOnce     sync.Once
+}
+
+type progressReaderOption func(*progressReader)
+
+// WithBufferSize reads the underlying stream through a bufio.Reader of n bytes.
+func WithBufferSize(n int) progressReaderOption {
+	return func(p *progressReader) {
+		p.BufferSizeBytes = n
+	}
+}
+
+// WithStallTimeout calls onStall once when no bytes have been read for d.
+func WithStallTimeout(d time.Duration, onStall func()) progressReaderOption {
+	return func(p *progressReader) {
+		p.stallTimeout = d
+		p.onStall = onStall
+	}
+}
+
+// WithHasher feeds every byte read into h, so the content hash is available from Sum once the
+// stream has been consumed.
+func WithHasher(h hash.Hash) progressReaderOption {
+	return func(p *progressReader) {
+		p.hasher = h
+	}
+}
+
+// WithDebug reports the number of reads from the underlying stream with the progress.
+func WithDebug() progressReaderOption {
+	return func(p *progressReader) {
+		p.Debug = true
+	}
 }
 
-func newProgressReader(rc io.ReadCloser, total int64, cb func(int64, int64, float64)) *progressReader {
-	return &progressReader{
+func newProgressReader(rc io.ReadCloser, total int64, cb func(progressSnapshot), opts ...progressReaderOption) *progressReader {
+	p := &progressReader{
 		rc:       rc,
 		total:    total,
 		lastTime: time.Now(),
 		callback: cb,
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.BufferSizeBytes > 0 {
+		p.rc = &bufferedProgressReader{
+			Reader: bufio.NewReaderSize(rc, p.BufferSizeBytes),
+			closer: rc,
+		}
+	}
+	if p.stallTimeout > 0 && p.onStall != nil {
+		p.lastActivity.Store(time.Now().UnixNano())
+		p.stopWatch = make(chan struct{})
+		go p.watchStall()
+	}
+	return p
+}
+
+// watchStall runs alongside the reads, because a frozen stream blocks Read and
+// never gives report a chance to notice that p.read has stopped changing.
+func (p *progressReader) watchStall() {
+	interval := p.stallTimeout / 4
+	if interval < 100*time.Millisecond {
+		interval = 100 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stopWatch:
+			return
+		case now := <-ticker.C:
+			if now.Sub(time.Unix(0, p.lastActivity.Load())) >= p.stallTimeout {
+				p.onStall()
+				return
+			}
+		}
+	}
+}
+
+func (p *progressReader) stopStallWatch() {
+	if p.stopWatch == nil {
+		return
+	}
+	p.stopOnce.Do(func() {
+		close(p.stopWatch)
+	})
+}
+
+// bufferedProgressReader pairs a bufio.Reader with the stream it wraps so the
+// original stream is still closed.
+type bufferedProgressReader struct {
+	*bufio.Reader
+	closer io.Closer
+}
+
+func (b *bufferedProgressReader) Close() error {
+	return b.closer.Close()
 }
 
 func (p *progressReader) Read(b []byte) (int, error) {
+	p.readCalls.Add(1)
 	n, err := p.rc.Read(b)
 	if n > 0 {
+		if p.hasher != nil {
+			p.hasher.Write(b[:n])
+		}
 		p.read += int64(n)
+		p.lastActivity.Store(time.Now().UnixNano())
 		p.report(false)
 	}
 	if err == io.EOF {
+		p.stopStallWatch()
 		p.report(true)
 	}
 	return n, err
 }
 
+// WriteTo lets io.Copy use the underlying stream's io.WriterTo fast path while still
+// accounting every chunk written, falling back to plain reads otherwise.
+func (p *progressReader) WriteTo(w io.Writer) (int64, error) {
+	wt, ok := p.rc.(io.WriterTo)
+	if !ok {
+		// Hide WriteTo from io.Copy so it doesn't call back into this method
+		return io.Copy(w, struct{ io.Reader }{p})
+	}
+	n, err := wt.WriteTo(&progressWriter{w: w, p: p})
+	if err == nil {
+		// WriteTo only returns a nil error once the stream is exhausted
+		p.stopStallWatch()
+		p.report(true)
+	}
+	return n, err
+}
+
+// progressWriter counts the bytes a WriterTo hands over as if they had been read.
+type progressWriter struct {
+	w io.Writer
+	p *progressReader
+}
+
+func (pw *progressWriter) Write(b []byte) (int, error) {
+	// Each chunk handed over stands for one read of the underlying stream
+	pw.p.readCalls.Add(1)
+	n, err := pw.w.Write(b)
+	if n > 0 {
+		if pw.p.hasher != nil {
+			pw.p.hasher.Write(b[:n])
+		}
+		pw.p.read += int64(n)
+		pw.p.lastActivity.Store(time.Now().UnixNano())
+		pw.p.report(false)
+	}
+	return n, err
+}
+
+// report sends a progress snapshot, at most every 500ms unless forced. The forced report
+// sent at EOF or Close is the final one; any report after it is a no-op.
 func (p *progressReader) report(force bool) {
-	if p.callback == nil {
+	if p.callback == nil || p.finished {
 		return
 	}
+	if force {
+		p.finished = true
+	}
 	now := time.Now()
 	if !force && now.Sub(p.lastTime) < 500*time.Millisecond {
 		return
@@ -563,15 +2154,96 @@ func (p *progressReader) report(force bool) {
 	if deltaTime > 0 {
 		speed = float64(deltaBytes) / deltaTime
 	}
-	p.callback(p.read, p.total, speed)
+	// Smooth the per-interval speed with an exponentially weighted moving average,
+	// since short intervals make the instantaneous value very jittery.
+	if p.hasSpeed {
+		p.ewmaSpeed = speedSmoothingFactor*speed + (1-speedSmoothingFactor)*p.ewmaSpeed
+		p.PeakSpeedBytesPerSec = max(p.PeakSpeedBytesPerSec, speed)
+		p.MinSpeedBytesPerSec = min(p.MinSpeedBytesPerSec, speed)
+	} else {
+		p.ewmaSpeed = speed
+		p.PeakSpeedBytesPerSec = speed
+		p.MinSpeedBytesPerSec = speed
+		p.hasSpeed = true
+	}
+	snapshot := progressSnapshot{
+		BytesRead:    p.read,
+		Total:        p.total,
+		InstantSpeed: speed,
+		AvgSpeed:     p.ewmaSpeed,
+	}
+	if p.Debug {
+		snapshot.ReadCalls = p.ReadCalls()
+	}
+	p.callback(snapshot)
 	p.lastTime = now
 	p.lastBytes = p.read
 }
 
 func (p *progressReader) Close() error {
+	p.stopStallWatch()
+	p.report(true)
 	return p.rc.Close()
 }
 
 func (p *progressReader) BytesRead() int64 {
 	return p.read
 }
+
+// Sum returns the hash of the bytes read so far, or nil without WithHasher.
+func (p *progressReader) Sum() []byte {
+	if p.hasher == nil {
+		return nil
+	}
+	return p.hasher.Sum(nil)
+}
+
+// PeakSpeed returns the fastest speed reported so far, in bytes per second.
+func (p *progressReader) PeakSpeed() float64 {
+	return p.PeakSpeedBytesPerSec
+}
+
+// MinSpeed returns the slowest speed reported so far, in bytes per second.
+func (p *progressReader) MinSpeed() float64 {
+	return p.MinSpeedBytesPerSec
+}
+
+// ReadCalls returns how many reads were made from the underlying stream so far.
+func (p *progressReader) ReadCalls() int64 {
+	return p.readCalls.Load()
+}
+
+// throttledReader limits reads from r to roughly bps bytes per second.
+type throttledReader struct {
+	ctx   context.Context
+	r     io.Reader
+	bps   int64
+	read  int64
+	start time.Time
+}
+
+func newThrottledReader(ctx context.Context, r io.Reader, bps int64) *throttledReader {
+	return &throttledReader{ctx: ctx, r: r, bps: bps, start: time.Now()}
+}
+
+func (t *throttledReader) Read(b []byte) (int, error) {
+	if int64(len(b)) > t.bps {
+		b = b[:t.bps]
+	}
+	n, err := t.r.Read(b)
+	t.read += int64(n)
+
+	expected := time.Duration(float64(t.read) / float64(t.bps) * float64(time.Second))
+	if wait := expected - time.Since(t.start); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-t.ctx.Done():
+			if err == nil {
+				err = t.ctx.Err()
+			}
+		case <-timer.C:
+		}
+	}
+	return n, err
+}
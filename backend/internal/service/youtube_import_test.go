@@ -0,0 +1,235 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/kkdai/youtube/v2"
+)
+
+type notFoundError struct{ notFound bool }
+
+func (e notFoundError) Error() string    { return "lookup failed" }
+func (e notFoundError) IsNotFound() bool { return e.notFound }
+
+func TestIsNotFoundError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"typed s3 error", fmt.Errorf("head object: %w", &types.NoSuchKey{}), true},
+		{"not found interface", notFoundError{notFound: true}, true},
+		{"interface reporting found", notFoundError{notFound: false}, false},
+		{"untyped backend message", errors.New("operation error S3: HeadObject, StatusCode: 404, NotFound"), true},
+		{"other error", errors.New("access denied"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isNotFoundError(tt.err); got != tt.want {
+				t.Errorf("isNotFoundError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeObjectPrefix(t *testing.T) {
+	tests := []struct {
+		prefix  string
+		want    string
+		wantErr bool
+	}{
+		{prefix: "", want: ""},
+		{prefix: "/", want: ""},
+		{prefix: "videos", want: "videos/"},
+		{prefix: " /videos//youtube/ ", want: "videos/youtube/"},
+		{prefix: `Videos\YouTube\2024`, want: "Videos/YouTube/2024/"},
+		{prefix: `\\server\share\`, want: "server/share/"},
+		{prefix: "videos/../secrets", wantErr: true},
+		{prefix: `videos\..\secrets`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			got, err := normalizeObjectPrefix(tt.prefix)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidPrefix) {
+					t.Fatalf("normalizeObjectPrefix(%q) error = %v, want ErrInvalidPrefix", tt.prefix, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalizeObjectPrefix(%q) returned %v", tt.prefix, err)
+			}
+			if got != tt.want {
+				t.Errorf("normalizeObjectPrefix(%q) = %q, want %q", tt.prefix, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtensionFromMime(t *testing.T) {
+	tests := []struct {
+		mimeType string
+		want     string
+	}{
+		{`video/mp4; codecs="avc1.640028"`, ".mp4"},
+		{`audio/mp4; codecs="mp4a.40.2"`, ".m4a"},
+		{`video/webm; codecs="vp9"`, ".webm"},
+		{`audio/webm; codecs="opus"`, ".weba"},
+		{`video/ogg; codecs="theora"`, ".ogv"},
+		{`audio/ogg; codecs="vorbis"`, ".oga"},
+		{"video/x-matroska", ".mkv"},
+		{"application/octet-stream", ".bin"},
+		{"", ".bin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mimeType, func(t *testing.T) {
+			if got := extensionFromMime(tt.mimeType); got != tt.want {
+				t.Errorf("extensionFromMime(%q) = %q, want %q", tt.mimeType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProgressReaderSum(t *testing.T) {
+	data := strings.Repeat("bucketbird", 1000)
+	want := sha256.Sum256([]byte(data))
+
+	tests := []struct {
+		name string
+		copy func(dst io.Writer, p *progressReader) error
+	}{
+		{"read", func(dst io.Writer, p *progressReader) error {
+			_, err := io.Copy(dst, struct{ io.Reader }{p})
+			return err
+		}},
+		{"write to", func(dst io.Writer, p *progressReader) error {
+			_, err := p.WriteTo(dst)
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newProgressReader(io.NopCloser(strings.NewReader(data)), int64(len(data)), nil,
+				WithBufferSize(512), WithHasher(sha256.New()))
+			if err := tt.copy(io.Discard, p); err != nil {
+				t.Fatalf("copy: %v", err)
+			}
+			if !bytes.Equal(p.Sum(), want[:]) {
+				t.Errorf("Sum() = %x, want %x", p.Sum(), want)
+			}
+		})
+	}
+
+	if sum := newProgressReader(io.NopCloser(strings.NewReader(data)), 0, nil).Sum(); sum != nil {
+		t.Errorf("Sum() without a hasher = %x, want nil", sum)
+	}
+}
+
+func TestValidateConcurrency(t *testing.T) {
+	tests := []struct {
+		concurrency int
+		valid       bool
+	}{
+		{0, true},
+		{1, true},
+		{maxImportConcurrency, true},
+		{-1, false},
+		{maxImportConcurrency + 1, false},
+		{10000, false},
+	}
+
+	for _, tt := range tests {
+		input := YouTubeImportInput{URL: "https://www.youtube.com/watch?v=video", Concurrency: tt.concurrency}
+		if errs := input.Validate(); (len(errs) == 0) != tt.valid {
+			t.Errorf("Concurrency %d: errors = %v, want valid = %v", tt.concurrency, errs, tt.valid)
+		}
+	}
+}
+
+func TestIsYouTubeUnavailableError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"private", youtube.ErrVideoPrivate, true},
+		{"login required", fmt.Errorf("get video: %w", youtube.ErrLoginRequired), true},
+		{"embedding disabled", youtube.ErrNotPlayableInEmbed, true},
+		{"playability status", &youtube.ErrPlayabiltyStatus{Status: "UNPLAYABLE", Reason: "Video unavailable"}, true},
+		{"storage error", errors.New("put object: bucket is private"), false},
+		{"network error", errors.New("service unavailable"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isYouTubeUnavailableError(tt.err); got != tt.want {
+				t.Errorf("isYouTubeUnavailableError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildYouTubeBaseNameTruncatesCJK(t *testing.T) {
+	// Every rune is 3 bytes, so most limits fall in the middle of a rune
+	title := strings.Repeat("日本語", 100)
+
+	for _, maxLength := range []int{1, 2, 10, 100, 255} {
+		input := YouTubeImportInput{SanitizeMode: SanitizeModeUnicode, MaxFilenameLengthBytes: maxLength}
+		name := buildYouTubeBaseName(title, input)
+		if !utf8.ValidString(name) {
+			t.Errorf("max %d: name %q is not valid UTF-8", maxLength, name)
+		}
+		if len(name) > maxLength {
+			t.Errorf("max %d: name is %d bytes long", maxLength, len(name))
+		}
+		if want := maxLength / 3 * 3; len(name) != want {
+			t.Errorf("max %d: name is %d bytes long, want %d", maxLength, len(name), want)
+		}
+	}
+}
+
+func TestValidateImportNoteBytes(t *testing.T) {
+	tests := []struct {
+		note  string
+		valid bool
+	}{
+		{strings.Repeat("a", maxImportNoteBytes), true},
+		{strings.Repeat("a", maxImportNoteBytes+1), false},
+		// 100 characters but 400 bytes
+		{strings.Repeat("😀", 100), false},
+	}
+
+	for _, tt := range tests {
+		input := YouTubeImportInput{URL: "https://www.youtube.com/watch?v=video", ImportNote: tt.note}
+		if errs := input.Validate(); (len(errs) == 0) != tt.valid {
+			t.Errorf("ImportNote of %d bytes: errors = %v, want valid = %v", len(tt.note), errs, tt.valid)
+		}
+	}
+}
+
+func TestCheckUserMetadataSize(t *testing.T) {
+	metadata := map[string]string{
+		"video-title": strings.Repeat("a", 1000),
+		"description": strings.Repeat("b", 1000),
+	}
+
+	if err := checkUserMetadataSize(metadata, 0); err != nil {
+		t.Fatalf("checkUserMetadataSize() error = %v", err)
+	}
+	if err := checkUserMetadataSize(metadata, 100); !errors.Is(err, ErrMetadataTooLarge) {
+		t.Errorf("checkUserMetadataSize() with reserved bytes error = %v, want ErrMetadataTooLarge", err)
+	}
+}
@@ -0,0 +1,709 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bucketbird/backend/internal/storage"
+
+	"github.com/google/uuid"
+	"github.com/kkdai/youtube/v2"
+)
+
+// DeleteImportedVideoResult lists the objects removed for a deleted YouTube video
+type DeleteImportedVideoResult struct {
+	DeletedKeys []string `json:"deletedKeys"`
+}
+
+type importedVideoCacheKey struct{}
+
+// importedVideoCache memoises ListImportedYouTubeVideos results for the lifetime of a context
+type importedVideoCache struct {
+	mu      sync.Mutex
+	entries map[string][]YouTubeImportedItem
+}
+
+// WithImportedVideoCache returns a context that caches imported video listings, so a request
+// that looks up several videos only issues the HeadObject calls once per bucket prefix.
+func WithImportedVideoCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, importedVideoCacheKey{}, &importedVideoCache{
+		entries: make(map[string][]YouTubeImportedItem),
+	})
+}
+
+func importedVideoCacheFromContext(ctx context.Context) *importedVideoCache {
+	cache, _ := ctx.Value(importedVideoCacheKey{}).(*importedVideoCache)
+	return cache
+}
+
+// ListImportedYouTubeVideos lists the objects under prefix that were created by a YouTube import
+func (s *BucketService) ListImportedYouTubeVideos(ctx context.Context, ref BucketRef, prefix string, encryptionKey []byte) ([]YouTubeImportedItem, error) {
+	prefix, err := normalizeObjectPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := importedVideoCacheFromContext(ctx)
+	cacheKey := ref.BucketID.String() + "/" + prefix
+	if cache != nil {
+		cache.mu.Lock()
+		cached, ok := cache.entries[cacheKey]
+		cache.mu.Unlock()
+		if ok {
+			return cached, nil
+		}
+	}
+
+	bucketName, err := s.getBucketName(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := store.ListAllObjects(ctx, bucketName, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]YouTubeImportedItem, 0)
+	for _, obj := range objects {
+		if obj.Key == nil || strings.HasSuffix(*obj.Key, "/") {
+			continue
+		}
+		key := *obj.Key
+
+		head, err := store.HeadObject(ctx, bucketName, key)
+		if err != nil {
+			if isNotFoundError(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		videoID := metadataValue(head.Metadata, s.metadataKey(youtubeVideoIDMetadataKey))
+		if videoID == "" {
+			continue
+		}
+
+		item := YouTubeImportedItem{
+			Title:       metadataValue(head.Metadata, s.metadataKey(youtubeVideoTitleMetadataKey)),
+			Key:         key,
+			VideoID:     videoID,
+			SizeBytes:   awsInt64Value(head.ContentLength),
+			ContentType: awsStringValue(head.ContentType),
+			ETag:        strings.Trim(awsStringValue(head.ETag), "\""),
+			Author:      metadataValue(head.Metadata, s.metadataKey(youtubeChannelMetadataKey)),
+		}
+		if resolution := metadataValue(head.Metadata, s.metadataKey(youtubeResolutionMetadataKey)); resolution != "" {
+			fmt.Sscanf(resolution, "%dx%d", &item.Width, &item.Height)
+		}
+		if duration := metadataValue(head.Metadata, s.metadataKey(youtubeDurationMetadataKey)); duration != "" {
+			item.DurationSeconds, _ = strconv.Atoi(duration)
+		}
+		if views := metadataValue(head.Metadata, s.metadataKey(youtubeViewCountMetadataKey)); views != "" {
+			item.ViewCount, _ = strconv.ParseInt(views, 10, 64)
+		}
+		if likes := metadataValue(head.Metadata, s.metadataKey(youtubeLikeCountMetadataKey)); likes != "" {
+			item.LikeCount, _ = strconv.ParseInt(likes, 10, 64)
+		}
+		item.ImportedBy = metadataValue(head.Metadata, s.metadataKey(youtubeImportedByMetadataKey))
+		if importedAt := metadataValue(head.Metadata, s.metadataKey(youtubeImportedAtMetadataKey)); importedAt != "" {
+			item.ImportedAt, _ = time.Parse(time.RFC3339, importedAt)
+		}
+		items = append(items, item)
+	}
+
+	if cache != nil {
+		cache.mu.Lock()
+		cache.entries[cacheKey] = items
+		cache.mu.Unlock()
+	}
+
+	return items, nil
+}
+
+// FindImportedVideoByID returns the object under prefix that holds the given YouTube video
+func (s *BucketService) FindImportedVideoByID(ctx context.Context, ref BucketRef, prefix, videoID string, encryptionKey []byte) (*YouTubeImportedItem, error) {
+	items, err := s.ListImportedYouTubeVideos(ctx, ref, prefix, encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		if items[i].VideoID == videoID {
+			item := items[i]
+			return &item, nil
+		}
+	}
+
+	return nil, ErrImportedVideoNotFound
+}
+
+// ListImportedVideosByChannel lists the videos under prefix that were published by the named
+// channel, compared case-insensitively, most recently imported first
+func (s *BucketService) ListImportedVideosByChannel(ctx context.Context, ref BucketRef, prefix, channelName string, encryptionKey []byte) ([]YouTubeImportedItem, error) {
+	items, err := s.ListImportedYouTubeVideos(ctx, ref, prefix, encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// items may be shared through the context cache, so filter into a new slice
+	matches := make([]YouTubeImportedItem, 0)
+	for _, item := range items {
+		if strings.EqualFold(item.Author, channelName) {
+			matches = append(matches, item)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].ImportedAt.After(matches[j].ImportedAt)
+	})
+
+	return matches, nil
+}
+
+// DeleteImportedYouTubeVideo removes every object tagged with the given YouTube video ID,
+// including sidecar files such as thumbnails and subtitles
+func (s *BucketService) DeleteImportedYouTubeVideo(ctx context.Context, ref BucketRef, videoID string, encryptionKey []byte) (*DeleteImportedVideoResult, error) {
+	items, err := s.ListImportedYouTubeVideos(ctx, ref, "", encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0)
+	for _, item := range items {
+		if item.VideoID == videoID {
+			keys = append(keys, item.Key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, ErrImportedVideoNotFound
+	}
+
+	bucketName, err := s.getBucketName(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := store.DeleteObjects(ctx, bucketName, keys); err != nil {
+		return nil, err
+	}
+
+	removed := make(map[string][]string)
+	for _, key := range keys {
+		for _, prefix := range indexPrefixesOf(key) {
+			removed[prefix] = append(removed[prefix], videoID)
+		}
+	}
+	s.pruneImportedVideoIndexes(ctx, store, bucketName, removed)
+
+	s.recalculateBucketSizeAsync(ref, encryptionKey, "deleting imported video")
+
+	return &DeleteImportedVideoResult{DeletedKeys: keys}, nil
+}
+
+// BulkDeleteByPlaylist removes every imported object that belongs to a video of the playlist.
+// confirm receives the keys about to be deleted and must return true, otherwise nothing is
+// deleted and ErrDeleteNotConfirmed is returned. Videos of the playlist that have since become
+// unavailable are included, since they may have been imported while they were still public.
+func (s *BucketService) BulkDeleteByPlaylist(
+	ctx context.Context,
+	ref BucketRef,
+	playlistURL string,
+	encryptionKey []byte,
+	confirm func([]string) bool,
+) (deleted int, err error) {
+
+	client := s.youtubeClient
+	if client == nil {
+		client = &youtube.Client{}
+	}
+	client = s.pacedYouTubeClient(client)
+
+	resolved := &YouTubeImportResult{}
+	videos, _, err := s.resolveYouTubeVideos(ctx, client, playlistURL, resolved, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	videoIDs := make(map[string]bool, len(videos))
+	for _, video := range videos {
+		videoIDs[video.ID] = true
+	}
+	for _, unresolved := range append(resolved.Unavailable, resolved.Errors...) {
+		if unresolved.VideoID != "" {
+			videoIDs[unresolved.VideoID] = true
+		}
+	}
+
+	items, err := s.ListImportedYouTubeVideos(ctx, ref, "", encryptionKey)
+	if err != nil {
+		return 0, err
+	}
+
+	keys := make([]string, 0)
+	removed := make(map[string][]string)
+	for _, item := range items {
+		if videoIDs[item.VideoID] {
+			keys = append(keys, item.Key)
+			for _, prefix := range indexPrefixesOf(item.Key) {
+				removed[prefix] = append(removed[prefix], item.VideoID)
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	if confirm == nil || !confirm(keys) {
+		return 0, ErrDeleteNotConfirmed
+	}
+
+	bucketName, err := s.getBucketName(ctx, ref)
+	if err != nil {
+		return 0, err
+	}
+
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
+	if err != nil {
+		return 0, err
+	}
+
+	// Delete in one batch instead of per video so the bucket size is only recalculated once
+	if err := store.DeleteObjects(ctx, bucketName, keys); err != nil {
+		return 0, err
+	}
+	s.pruneImportedVideoIndexes(ctx, store, bucketName, removed)
+
+	s.recalculateBucketSizeAsync(ref, encryptionKey, "deleting playlist videos")
+
+	return len(keys), nil
+}
+
+// TagImportSession sets tags on every object created by the import with the given session ID,
+// e.g. to mark a batch as reviewed. The tags replace any the objects already had.
+func (s *BucketService) TagImportSession(ctx context.Context, ref BucketRef, sessionID uuid.UUID, tags map[string]string, encryptionKey []byte) (updated int, err error) {
+	bucketName, err := s.getBucketName(ctx, ref)
+	if err != nil {
+		return 0, err
+	}
+
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
+	if err != nil {
+		return 0, err
+	}
+
+	objects, err := store.ListAllObjects(ctx, bucketName, "")
+	if err != nil {
+		return 0, err
+	}
+
+	sessionKey := s.metadataKey(youtubeSessionIDMetadataKey)
+	for _, obj := range objects {
+		if obj.Key == nil || strings.HasSuffix(*obj.Key, "/") {
+			continue
+		}
+		key := *obj.Key
+
+		head, err := store.HeadObject(ctx, bucketName, key)
+		if err != nil {
+			if isNotFoundError(err) {
+				continue
+			}
+			return updated, err
+		}
+		if metadataValue(head.Metadata, sessionKey) != sessionID.String() {
+			continue
+		}
+
+		if err := store.PutObjectTagging(ctx, bucketName, key, tags); err != nil {
+			return updated, fmt.Errorf("tag %s: %w", key, err)
+		}
+		updated++
+	}
+
+	return updated, nil
+}
+
+// PresignImportResult generates presigned download URLs for every imported item of an import
+// result, keyed by object key, so clients can play the videos right after the import
+func (s *BucketService) PresignImportResult(ctx context.Context, ref BucketRef, result *YouTubeImportResult, expiresIn time.Duration, encryptionKey []byte) (map[string]string, error) {
+	urls := make(map[string]string)
+	if result == nil || result.DryRun || len(result.Items) == 0 {
+		return urls, nil
+	}
+
+	if err := s.rejectDemoUser(ctx, ref.UserID); err != nil {
+		return nil, err
+	}
+
+	bucketName, err := s.getBucketName(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range result.Items {
+		if _, ok := urls[item.Key]; ok {
+			continue
+		}
+		presigned, err := store.PresignObject(ctx, storage.PresignInput{
+			Bucket:    bucketName,
+			Key:       item.Key,
+			Method:    http.MethodGet,
+			ExpiresIn: expiresIn,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("presign %s: %w", item.Key, err)
+		}
+		urls[item.Key] = presigned.URL
+	}
+
+	return urls, nil
+}
+
+// importedVideoIndexName is the object under an import prefix that lists the imported video IDs
+const importedVideoIndexName = ".bucketbird-index.json"
+
+// GetImportedVideoIDs reads the video ID index that imports maintain for prefix. A prefix that
+// was never imported into has an empty index.
+func (s *BucketService) GetImportedVideoIDs(ctx context.Context, ref BucketRef, prefix string, encryptionKey []byte) ([]string, error) {
+	prefix, err := normalizeObjectPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	bucketName, err := s.getBucketName(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := readImportedVideoIndex(ctx, store, bucketName, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if ids == nil {
+		ids = []string{}
+	}
+	return ids, nil
+}
+
+// readImportedVideoIndex returns the IDs listed in the index of prefix, or nil when the prefix
+// has no index
+func readImportedVideoIndex(ctx context.Context, store storage.ObjectStoreClient, bucketName, prefix string) ([]string, error) {
+	obj, err := store.GetObject(ctx, bucketName, prefix+importedVideoIndexName)
+	if err != nil {
+		if isNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer obj.Body.Close()
+
+	ids := make([]string, 0)
+	if err := json.NewDecoder(obj.Body).Decode(&ids); err != nil {
+		return nil, fmt.Errorf("decode imported video index: %w", err)
+	}
+	return ids, nil
+}
+
+// updateImportedVideoIndex merges the IDs of items into the index of prefix
+func (s *BucketService) updateImportedVideoIndex(ctx context.Context, store storage.ObjectStoreClient, bucketName, prefix string, items []YouTubeImportedItem) error {
+	added := make([]string, 0, len(items))
+	for _, item := range items {
+		if item.VideoID != "" {
+			added = append(added, item.VideoID)
+		}
+	}
+	return s.editImportedVideoIndex(ctx, store, bucketName, prefix, added, nil)
+}
+
+// pruneImportedVideoIndexes removes video IDs from the indexes they are listed in. removed maps
+// an index prefix to the IDs to drop from it; prefixes without an index are skipped.
+func (s *BucketService) pruneImportedVideoIndexes(ctx context.Context, store storage.ObjectStoreClient, bucketName string, removed map[string][]string) {
+	for prefix, ids := range removed {
+		if err := s.editImportedVideoIndex(ctx, store, bucketName, prefix, nil, ids); err != nil {
+			s.logger.Warn("failed to prune imported video index", "prefix", prefix, "error", err)
+		}
+	}
+}
+
+// indexPrefixesOf returns the prefixes whose index may list the object at key: the bucket root
+// and every folder above the object
+func indexPrefixesOf(key string) []string {
+	prefixes := []string{""}
+	for i, r := range key {
+		if r == '/' {
+			prefixes = append(prefixes, key[:i+1])
+		}
+	}
+	return prefixes
+}
+
+// editImportedVideoIndex adds and removes video IDs in the index of prefix. Only removing IDs
+// never creates an index. Updates are serialized within the process; two servers updating the
+// same index at once can still lose one update, which later imports or migrations repair.
+func (s *BucketService) editImportedVideoIndex(ctx context.Context, store storage.ObjectStoreClient, bucketName, prefix string, added, removed []string) error {
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+
+	ids, err := readImportedVideoIndex(ctx, store, bucketName, prefix)
+	if err != nil {
+		return err
+	}
+	if ids == nil && len(added) == 0 {
+		return nil
+	}
+
+	listed := make(map[string]bool, len(ids)+len(added))
+	for _, id := range ids {
+		listed[id] = true
+	}
+	changed := false
+	for _, id := range added {
+		if !listed[id] {
+			listed[id] = true
+			changed = true
+		}
+	}
+	for _, id := range removed {
+		if listed[id] {
+			delete(listed, id)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	ids = make([]string, 0, len(listed))
+	for id := range listed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	_, err = store.PutObject(ctx, bucketName, prefix+importedVideoIndexName, bytes.NewReader(data), "application/json", nil)
+	return err
+}
+
+// MigrationProgress reports a video moved by MigrateImportedVideos
+type MigrationProgress struct {
+	SourceKey      string `json:"sourceKey"`
+	DestinationKey string `json:"destinationKey"`
+	Moved          int    `json:"moved"`
+	Total          int    `json:"total"`
+}
+
+// MigrateImportedVideos moves every imported video under sourcePrefix to destPrefix, keeping
+// the path below the prefix. Each move is reported on progress when it is not nil. The first
+// failure aborts the migration and the number of videos moved so far is returned with it.
+func (s *BucketService) MigrateImportedVideos(
+	ctx context.Context,
+	ref BucketRef,
+	sourcePrefix,
+	destPrefix string,
+	encryptionKey []byte,
+	progress chan<- MigrationProgress,
+) (moved int, err error) {
+
+	sourcePrefix, err = normalizeObjectPrefix(sourcePrefix)
+	if err != nil {
+		return 0, err
+	}
+	destPrefix, err = normalizeObjectPrefix(destPrefix)
+	if err != nil {
+		return 0, err
+	}
+	if sourcePrefix == destPrefix {
+		return 0, nil
+	}
+
+	items, err := s.ListImportedYouTubeVideos(ctx, ref, sourcePrefix, encryptionKey)
+	if err != nil {
+		return 0, err
+	}
+
+	bucketName, err := s.getBucketName(ctx, ref)
+	if err != nil {
+		return 0, err
+	}
+
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if moved == 0 {
+			return
+		}
+		// The migration may have been cancelled, but the moves it made must still be indexed
+		indexCtx := context.WithoutCancel(ctx)
+		if indexErr := s.updateImportedVideoIndex(indexCtx, store, bucketName, destPrefix, items[:moved]); indexErr != nil {
+			s.logger.Warn("failed to update imported video index after migration", "prefix", destPrefix, "error", indexErr)
+		}
+		// Drop the moved videos from the indexes above their old keys, except the ones that
+		// also cover the new keys (when destPrefix is inside sourcePrefix)
+		removed := make(map[string][]string)
+		for _, item := range items[:moved] {
+			for _, prefix := range indexPrefixesOf(item.Key) {
+				if !strings.HasPrefix(destPrefix, prefix) {
+					removed[prefix] = append(removed[prefix], item.VideoID)
+				}
+			}
+		}
+		s.pruneImportedVideoIndexes(indexCtx, store, bucketName, removed)
+	}()
+
+	for _, item := range items {
+		destKey := destPrefix + strings.TrimPrefix(item.Key, sourcePrefix)
+		if err := store.CopyObject(ctx, bucketName, item.Key, destKey); err != nil {
+			return moved, fmt.Errorf("copy %s to %s: %w", item.Key, destKey, err)
+		}
+		if err := store.DeleteObject(ctx, bucketName, item.Key); err != nil {
+			return moved, fmt.Errorf("delete %s after copying it: %w", item.Key, err)
+		}
+		moved++
+
+		if progress != nil {
+			select {
+			case progress <- MigrationProgress{SourceKey: item.Key, DestinationKey: destKey, Moved: moved, Total: len(items)}:
+			case <-ctx.Done():
+				return moved, ctx.Err()
+			}
+		}
+	}
+
+	return moved, nil
+}
+
+// UpdateImportedVideoMetadata refreshes the title, description and tags stored on an imported
+// video from YouTube, since creators may edit them after the video was archived. It returns the
+// old and new title for audit logging. Tags are only available from the YouTube Data API, so
+// without an API key (see WithYouTubeAPIKey) they are left as they are.
+func (s *BucketService) UpdateImportedVideoMetadata(ctx context.Context, ref BucketRef, videoID string, encryptionKey []byte) (oldTitle, newTitle string, err error) {
+	client := s.youtubeClient
+	if client == nil {
+		client = &youtube.Client{}
+	}
+	client = s.pacedYouTubeClient(client)
+
+	video, err := client.GetVideoContext(ctx, videoID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to load video: %w", err)
+	}
+
+	item, err := s.FindImportedVideoByID(ctx, ref, "", videoID, encryptionKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	bucketName, err := s.getBucketName(ctx, ref)
+	if err != nil {
+		return "", "", err
+	}
+
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	head, err := store.HeadObject(ctx, bucketName, item.Key)
+	if err != nil {
+		return "", "", err
+	}
+
+	metadata := make(map[string]string, len(head.Metadata)+2)
+	for key, value := range head.Metadata {
+		metadata[strings.ToLower(key)] = value
+	}
+	metadata[s.metadataKey(youtubeVideoTitleMetadataKey)] = video.Title
+	if description := descriptionMetadataValue(video.Description); description != "" {
+		metadata[s.metadataKey(youtubeDescriptionMetadataKey)] = description
+	} else {
+		delete(metadata, s.metadataKey(youtubeDescriptionMetadataKey))
+	}
+
+	tags, err := s.youtubeVideoTags(ctx, videoID)
+	switch {
+	case err == nil:
+		if value := tagsMetadataValue(tags); value != "" {
+			metadata[s.metadataKey(youtubeTagsMetadataKey)] = value
+		} else {
+			delete(metadata, s.metadataKey(youtubeTagsMetadataKey))
+		}
+	case errors.Is(err, ErrYouTubeAPIKeyMissing):
+		s.logger.Warn("youtube data api key is not configured, keeping stored tags", "video_id", videoID)
+	default:
+		return "", "", fmt.Errorf("failed to load video tags: %w", err)
+	}
+
+	if err := checkUserMetadataSize(metadata, 0); err != nil {
+		return "", "", err
+	}
+
+	// The copy replaces the object's headers too, so pass the ones the import set again
+	if err := store.ReplaceObjectMetadata(ctx, bucketName, item.Key, awsStringValue(head.ContentType), metadata, storage.ObjectOptionsFromHead(head)...); err != nil {
+		return "", "", err
+	}
+
+	return item.Title, video.Title, nil
+}
+
+// tagsMetadataValue joins tags with commas, dropping the ones that don't fit into
+// maxTagsMetadataBytes
+func tagsMetadataValue(tags []string) string {
+	var b strings.Builder
+	for _, tag := range tags {
+		tag = strings.Join(strings.Fields(strings.ReplaceAll(tag, ",", " ")), " ")
+		if tag == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			if b.Len()+1+len(tag) > maxTagsMetadataBytes {
+				break
+			}
+			b.WriteString(",")
+		} else if len(tag) > maxTagsMetadataBytes {
+			break
+		}
+		b.WriteString(tag)
+	}
+	return b.String()
+}
+
+// descriptionMetadataValue flattens a video description into a single line, since metadata is
+// sent as HTTP headers, and caps its length
+func descriptionMetadataValue(description string) string {
+	return truncateUTF8(strings.Join(strings.Fields(description), " "), maxDescriptionMetadataBytes)
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kkdai/youtube/v2"
+)
+
+// defaultLiveStreamPollInterval is how often a running live stream is checked when the import
+// sets no LiveStreamPollIntervalSeconds
+const defaultLiveStreamPollInterval = time.Minute
+
+// isOngoingLiveStream reports whether video is a live stream that has not ended yet. YouTube only
+// serves an HLS manifest without a duration while the stream is running; once it ends, the
+// recording gets a duration and regular formats.
+func isOngoingLiveStream(video *youtube.Video) bool {
+	return video.HLSManifestURL != "" && video.Duration == 0
+}
+
+// waitForLiveStream polls YouTube until the live stream ends and returns the video of the
+// recording. While waiting, a "waiting-for-stream" event counts down to the next check every
+// second.
+func (s *BucketService) waitForLiveStream(ctx context.Context, run *youtubeImportRun, index int, video *youtube.Video) (*youtube.Video, error) {
+	interval := defaultLiveStreamPollInterval
+	if run.input.LiveStreamPollIntervalSeconds > 0 {
+		interval = time.Duration(run.input.LiveStreamPollIntervalSeconds) * time.Second
+	}
+
+	s.logger.Info("waiting for youtube live stream to end", "video_id", video.ID, "title", video.Title)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		nextCheck := time.Now().Add(interval)
+		for remaining := interval; remaining > 0; remaining = time.Until(nextCheck).Round(time.Second) {
+			emitProgress(run.progress, YouTubeImportProgress{
+				Stage:      StageWaitingForStream,
+				Kind:       run.kind,
+				Index:      index,
+				Total:      run.total,
+				VideoTitle: video.Title,
+				VideoID:    video.ID,
+				Message:    fmt.Sprintf("%q is still live, checking again in %s", video.Title, remaining),
+			})
+			select {
+			case <-ctx.Done():
+				return video, ctx.Err()
+			case <-ticker.C:
+			}
+		}
+
+		refreshed, err := run.client.GetVideoContext(ctx, video.ID)
+		if err != nil {
+			return video, fmt.Errorf("check live stream: %w", err)
+		}
+		if !isOngoingLiveStream(refreshed) {
+			return refreshed, nil
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// importManifestName is the object under an import prefix that describes the latest import
+const importManifestName = ".bucketbird-manifest.json"
+
+// ImportManifest records the last import into a prefix, so later runs can pick up from there
+type ImportManifest struct {
+	URL               string    `json:"url"`
+	DestinationPrefix string    `json:"destinationPrefix"`
+	StartedAt         time.Time `json:"startedAt"`
+	FinishedAt        time.Time `json:"finishedAt"`
+	Imported          int       `json:"imported"`
+	Skipped           int       `json:"skipped"`
+	Failed            int       `json:"failed"`
+	ImportNote        string    `json:"importNote,omitempty"`
+}
+
+// GetImportManifest returns the manifest of the latest import into prefix, or
+// ErrImportManifestNotFound when no import manifest was written yet
+func (s *BucketService) GetImportManifest(ctx context.Context, ref BucketRef, prefix string, encryptionKey []byte) (*ImportManifest, error) {
+	prefix, err := normalizeObjectPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	bucketName, err := s.getBucketName(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := store.GetObject(ctx, bucketName, prefix+importManifestName)
+	if err != nil {
+		if isNotFoundError(err) {
+			return nil, ErrImportManifestNotFound
+		}
+		return nil, err
+	}
+	defer obj.Body.Close()
+
+	var manifest ImportManifest
+	if err := json.NewDecoder(obj.Body).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("decode import manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+func (s *BucketService) writeImportManifest(ctx context.Context, ref BucketRef, manifest *ImportManifest, encryptionKey []byte) error {
+	prefix, err := normalizeObjectPrefix(manifest.DestinationPrefix)
+	if err != nil {
+		return err
+	}
+
+	bucketName, err := s.getBucketName(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	store, err := s.GetObjectStore(ctx, ref, encryptionKey)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	_, err = store.PutObject(ctx, bucketName, prefix+importManifestName, bytes.NewReader(data), "application/json", nil)
+	return err
+}
+
+// IncrementalImport imports only the videos published since the previous import into the same
+// destination prefix finished, then records this import in a new manifest. Without a previous
+// manifest everything is imported.
+func (s *BucketService) IncrementalImport(
+	ctx context.Context,
+	ref BucketRef,
+	input YouTubeImportInput,
+	encryptionKey []byte,
+	progress func(YouTubeImportProgress),
+) (*YouTubeImportResult, error) {
+	previous, err := s.GetImportManifest(ctx, ref, input.DestinationPrefix, encryptionKey)
+	switch {
+	case err == nil:
+		input.ImportAfter = previous.FinishedAt
+	case !errors.Is(err, ErrImportManifestNotFound):
+		return nil, err
+	}
+
+	startedAt := time.Now().UTC()
+	result, err := s.ImportYouTube(ctx, ref, input, encryptionKey, progress)
+	if err != nil {
+		return nil, err
+	}
+	if input.DryRun {
+		return result, nil
+	}
+
+	manifest := &ImportManifest{
+		URL:               input.URL,
+		DestinationPrefix: input.DestinationPrefix,
+		StartedAt:         startedAt,
+		FinishedAt:        time.Now().UTC(),
+		Imported:          result.Imported,
+		Skipped:           result.Skipped,
+		Failed:            len(result.Errors),
+		ImportNote:        input.ImportNote,
+	}
+	if err := s.writeImportManifest(ctx, ref, manifest, encryptionKey); err != nil {
+		return result, fmt.Errorf("write import manifest: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/kkdai/youtube/v2"
+)
+
+// defaultFFmpegPath is the ffmpeg binary used to mux separate video and audio streams
+const defaultFFmpegPath = "ffmpeg"
+
+// youtubeStreamPair is a video-only and an audio-only format that are muxed into one file.
+// YouTube serves its higher resolutions (typically 1080p and up) only this way.
+type youtubeStreamPair struct {
+	video *youtube.Format
+	audio *youtube.Format
+}
+
+// selectSeparateYouTubeFormats returns the streams to mux, or nil when a combined format will do
+func selectSeparateYouTubeFormats(video *youtube.Video, input YouTubeImportInput) *youtubeStreamPair {
+	if !input.AllowSeparateStreams || input.AudioOnly {
+		return nil
+	}
+
+	videoOnly := video.Formats.Select(func(format youtube.Format) bool {
+		return format.AudioChannels == 0 && strings.HasPrefix(format.MimeType, "video/")
+	})
+	audioOnly := video.Formats.Select(func(format youtube.Format) bool {
+		return format.AudioChannels > 0 && strings.HasPrefix(format.MimeType, "audio/")
+	})
+	if input.MaxBitrateKbps > 0 {
+		videoOnly = videoOnly.Select(func(format youtube.Format) bool {
+			return format.Bitrate <= input.MaxBitrateKbps*1000
+		})
+	}
+	if len(videoOnly) == 0 || len(audioOnly) == 0 {
+		return nil
+	}
+
+	combined := video.Formats.WithAudioChannels().Select(func(format youtube.Format) bool {
+		return strings.HasPrefix(format.MimeType, "video/")
+	})
+
+	if quality := strings.TrimSpace(input.Quality); quality != "" {
+		matchesQuality := func(format youtube.Format) bool {
+			return strings.EqualFold(format.QualityLabel, quality) || strings.EqualFold(format.Quality, quality)
+		}
+		if len(combined.Select(matchesQuality)) > 0 {
+			return nil
+		}
+		videoOnly = videoOnly.Select(matchesQuality)
+		if len(videoOnly) == 0 {
+			return nil
+		}
+	} else {
+		bestCombined := 0
+		for _, format := range combined {
+			bestCombined = max(bestCombined, format.Height)
+		}
+		bestVideoOnly := 0
+		for _, format := range videoOnly {
+			bestVideoOnly = max(bestVideoOnly, format.Height)
+		}
+		if bestVideoOnly <= bestCombined {
+			return nil
+		}
+	}
+
+	videoOnly.Sort()
+	sortByHDR(videoOnly, input.PreferHDR)
+	audioOnly.Sort()
+
+	// Stick to one container family when possible so the streams can be copied without remuxing
+	// into Matroska
+	videoFormat := videoOnly[0]
+	audioFormat := audioOnly[0]
+	for _, format := range audioOnly {
+		if sameContainerFamily(videoFormat.MimeType, format.MimeType) {
+			audioFormat = format
+			break
+		}
+	}
+
+	return &youtubeStreamPair{video: &videoFormat, audio: &audioFormat}
+}
+
+// muxedFormat describes the file produced by muxing the pair, for naming and progress reporting
+func (p *youtubeStreamPair) muxedFormat() *youtube.Format {
+	format := *p.video
+	format.MimeType = p.outputMimeType()
+	format.AudioChannels = p.audio.AudioChannels
+	format.Bitrate = p.video.Bitrate + p.audio.Bitrate
+	if p.video.ContentLength > 0 && p.audio.ContentLength > 0 {
+		format.ContentLength = p.video.ContentLength + p.audio.ContentLength
+	} else {
+		format.ContentLength = 0
+	}
+	return &format
+}
+
+func (p *youtubeStreamPair) outputMimeType() string {
+	switch {
+	case strings.Contains(p.video.MimeType, "mp4") && strings.Contains(p.audio.MimeType, "mp4"):
+		return "video/mp4"
+	case strings.Contains(p.video.MimeType, "webm") && strings.Contains(p.audio.MimeType, "webm"):
+		return "video/webm"
+	default:
+		return "video/x-matroska"
+	}
+}
+
+func sameContainerFamily(a, b string) bool {
+	return (strings.Contains(a, "mp4") && strings.Contains(b, "mp4")) ||
+		(strings.Contains(a, "webm") && strings.Contains(b, "webm"))
+}
+
+// openSeparateYouTubeStreams opens both streams of the pair and muxes them through ffmpeg
+func (s *BucketService) openSeparateYouTubeStreams(ctx context.Context, client YouTubeClient, video *youtube.Video, pair *youtubeStreamPair) (io.ReadCloser, error) {
+	videoStream, _, err := client.GetStreamContext(ctx, video, pair.video)
+	if err != nil {
+		return nil, fmt.Errorf("open video stream: %w", err)
+	}
+	audioStream, _, err := client.GetStreamContext(ctx, video, pair.audio)
+	if err != nil {
+		videoStream.Close()
+		return nil, fmt.Errorf("open audio stream: %w", err)
+	}
+
+	muxed, err := startFFmpegMux(ctx, s.ffmpegPath, videoStream, audioStream, pair.outputMimeType())
+	if err != nil {
+		videoStream.Close()
+		audioStream.Close()
+		return nil, err
+	}
+	return muxed, nil
+}
+
+// ffmpegMuxStream is the output of an ffmpeg process reading its inputs from extra file
+// descriptors: the first input is pipe:3, the second pipe:4 and so on.
+type ffmpegMuxStream struct {
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	stderr bytes.Buffer
+	inputs []io.ReadCloser
+	// cleanup runs once ffmpeg has exited, e.g. to remove temporary files it read
+	cleanup func()
+
+	copies   sync.WaitGroup
+	copyMu   sync.Mutex
+	copyErr  error
+	waitOnce sync.Once
+	waitErr  error
+}
+
+// startFFmpegMux copies a video and an audio stream into one container
+func startFFmpegMux(ctx context.Context, ffmpegPath string, videoStream, audioStream io.ReadCloser, mimeType string) (*ffmpegMuxStream, error) {
+	args := []string{
+		"-i", "pipe:3",
+		"-i", "pipe:4",
+		"-map", "0:v:0", "-map", "1:a:0",
+		"-c", "copy",
+	}
+	switch mimeType {
+	case "video/mp4":
+		// MP4 normally seeks back to write the index; fragments allow writing to a pipe
+		args = append(args, "-movflags", "frag_keyframe+empty_moov", "-f", "mp4")
+	case "video/webm":
+		args = append(args, "-f", "webm")
+	default:
+		args = append(args, "-f", "matroska")
+	}
+	return startFFmpeg(ctx, ffmpegPath, args, videoStream, audioStream)
+}
+
+// startFFmpeg runs ffmpeg with args, which must refer to the inputs as pipe:3 onwards and leave
+// out the output; the result is written to stdout.
+func startFFmpeg(ctx context.Context, ffmpegPath string, args []string, inputs ...io.ReadCloser) (*ffmpegMuxStream, error) {
+	if ffmpegPath == "" {
+		ffmpegPath = defaultFFmpegPath
+	}
+	args = append([]string{"-hide_banner", "-loglevel", "error"}, args...)
+	args = append(args, "pipe:1")
+
+	readers := make([]*os.File, 0, len(inputs))
+	writers := make([]*os.File, 0, len(inputs))
+	closeAll := func(files []*os.File) {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	for range inputs {
+		r, w, err := os.Pipe()
+		if err != nil {
+			closeAll(readers)
+			closeAll(writers)
+			return nil, err
+		}
+		readers = append(readers, r)
+		writers = append(writers, w)
+	}
+
+	m := &ffmpegMuxStream{
+		cmd:    exec.CommandContext(ctx, ffmpegPath, args...),
+		inputs: inputs,
+	}
+	m.cmd.ExtraFiles = readers
+	m.cmd.Stderr = &m.stderr
+	var err error
+	m.stdout, err = m.cmd.StdoutPipe()
+	if err == nil {
+		err = m.cmd.Start()
+	}
+	// The child has its own copies of the read ends
+	closeAll(readers)
+	if err != nil {
+		closeAll(writers)
+		return nil, fmt.Errorf("start ffmpeg: %w", err)
+	}
+
+	m.copies.Add(len(inputs))
+	for i, input := range inputs {
+		go m.feed(writers[i], input)
+	}
+	return m, nil
+}
+
+// feed copies one input into its ffmpeg pipe. Closing the pipe signals the end of the input.
+func (m *ffmpegMuxStream) feed(w *os.File, r io.Reader) {
+	defer m.copies.Done()
+	_, err := io.Copy(w, r)
+	w.Close()
+	if err != nil && !errors.Is(err, os.ErrClosed) {
+		m.copyMu.Lock()
+		if m.copyErr == nil {
+			m.copyErr = err
+		}
+		m.copyMu.Unlock()
+	}
+}
+
+func (m *ffmpegMuxStream) Read(b []byte) (int, error) {
+	n, err := m.stdout.Read(b)
+	if err == io.EOF {
+		if waitErr := m.wait(); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// wait reaps ffmpeg and reports a failed input copy or a non-zero exit
+func (m *ffmpegMuxStream) wait() error {
+	m.waitOnce.Do(func() {
+		m.copies.Wait()
+		err := m.cmd.Wait()
+
+		m.copyMu.Lock()
+		copyErr := m.copyErr
+		m.copyMu.Unlock()
+
+		// A failing ffmpeg also breaks the input pipes, so its error is the more useful one
+		switch {
+		case err != nil:
+			m.waitErr = fmt.Errorf("ffmpeg: %w: %s", err, strings.TrimSpace(m.stderr.String()))
+		case copyErr != nil:
+			m.waitErr = fmt.Errorf("read youtube stream: %w", copyErr)
+		}
+	})
+	return m.waitErr
+}
+
+// Close stops ffmpeg if it is still running and closes both input streams
+func (m *ffmpegMuxStream) Close() error {
+	for _, input := range m.inputs {
+		input.Close()
+	}
+	// Killing an already finished process only returns an error
+	m.cmd.Process.Kill()
+	m.stdout.Close()
+	m.wait()
+	if m.cleanup != nil {
+		m.cleanup()
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/kkdai/youtube/v2"
+)
+
+// youtubeTokenTransport adds the visitor data header to every YouTube request and the PoToken
+// to stream requests served from googlevideo.com
+type youtubeTokenTransport struct {
+	base        http.RoundTripper
+	poToken     string
+	visitorData string
+}
+
+func (t *youtubeTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	req.Header.Set("X-Goog-Visitor-Id", t.visitorData)
+	if strings.HasSuffix(req.URL.Hostname(), ".googlevideo.com") {
+		query := req.URL.Query()
+		query.Set("pot", t.poToken)
+		req.URL.RawQuery = query.Encode()
+	}
+	return t.base.RoundTrip(req)
+}
+
+// withYouTubeTokens returns a copy of a *youtube.Client that sends the import's PoToken and visitor data
+func (s *BucketService) withYouTubeTokens(client YouTubeClient, input YouTubeImportInput) YouTubeClient {
+	if input.PoToken == "" {
+		return client
+	}
+
+	ytClient, ok := client.(*youtube.Client)
+	if !ok {
+		s.logger.Warn("youtube client does not support po tokens, ignoring them")
+		return client
+	}
+
+	httpClient := &http.Client{}
+	if ytClient.HTTPClient != nil {
+		*httpClient = *ytClient.HTTPClient
+	}
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	httpClient.Transport = &youtubeTokenTransport{
+		base:        base,
+		poToken:     input.PoToken,
+		visitorData: input.VisitorData,
+	}
+
+	return &youtube.Client{
+		HTTPClient:  httpClient,
+		MaxRoutines: ytClient.MaxRoutines,
+		ChunkSize:   ytClient.ChunkSize,
+	}
+}
@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/kkdai/youtube/v2"
+)
+
+// youtubeDataAPIBaseURL is the root of the YouTube Data API v3
+const youtubeDataAPIBaseURL = "https://www.googleapis.com/youtube/v3/"
+
+// youtubeSearchPageSize is the largest page the search endpoint returns
+const youtubeSearchPageSize = 50
+
+// maxYouTubeSearchResults caps how many videos a single search import may request
+const maxYouTubeSearchResults = 200
+
+// WithYouTubeAPIKey sets the YouTube Data API key used for search imports and channel lookups
+func WithYouTubeAPIKey(key string) BucketServiceOption {
+	return func(s *BucketService) {
+		s.youtubeAPIKey = key
+	}
+}
+
+// ImportYouTubeSearch imports the top maxResults videos YouTube returns for query. The search
+// is resolved through the YouTube Data API, so the service needs an API key (WithYouTubeAPIKey).
+func (s *BucketService) ImportYouTubeSearch(
+	ctx context.Context,
+	ref BucketRef,
+	query string,
+	maxResults int,
+	input YouTubeImportInput,
+	encryptionKey []byte,
+	progress func(YouTubeImportProgress),
+) (*YouTubeImportResult, error) {
+	query = strings.TrimSpace(query)
+	var errs []ValidationError
+	if query == "" {
+		errs = append(errs, ValidationError{Field: "Query", Message: "is required"})
+	}
+	if maxResults <= 0 || maxResults > maxYouTubeSearchResults {
+		errs = append(errs, ValidationError{
+			Field:   "MaxResults",
+			Message: fmt.Sprintf("must be between 1 and %d", maxYouTubeSearchResults),
+		})
+	}
+	if err := validationError(errs); err != nil {
+		return nil, err
+	}
+	if s.youtubeAPIKey == "" {
+		return nil, ErrYouTubeAPIKeyMissing
+	}
+
+	emitProgress(progress, YouTubeImportProgress{
+		Stage:   StageResolving,
+		Kind:    "search",
+		Message: fmt.Sprintf("Searching YouTube for %q", query),
+	})
+
+	ids, err := s.searchYouTubeVideoIDs(ctx, query, maxResults)
+	if err != nil {
+		return nil, fmt.Errorf("youtube search failed: %w", err)
+	}
+
+	input.URL = ""
+	input.searchVideoIDs = ids
+	return s.ImportYouTube(ctx, ref, input, encryptionKey, progress)
+}
+
+type youtubeSearchResponse struct {
+	NextPageToken string `json:"nextPageToken"`
+	Items         []struct {
+		ID struct {
+			VideoID string `json:"videoId"`
+		} `json:"id"`
+	} `json:"items"`
+}
+
+// searchYouTubeVideoIDs pages through the search endpoint until maxResults video IDs are found
+func (s *BucketService) searchYouTubeVideoIDs(ctx context.Context, query string, maxResults int) ([]string, error) {
+	ids := make([]string, 0, maxResults)
+	pageToken := ""
+	for len(ids) < maxResults {
+		params := url.Values{}
+		params.Set("part", "id")
+		params.Set("type", "video")
+		params.Set("q", query)
+		params.Set("maxResults", strconv.Itoa(min(maxResults-len(ids), youtubeSearchPageSize)))
+		if pageToken != "" {
+			params.Set("pageToken", pageToken)
+		}
+
+		var page youtubeSearchResponse
+		if err := s.youtubeAPIGet(ctx, "search", params, &page); err != nil {
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			if item.ID.VideoID != "" && len(ids) < maxResults {
+				ids = append(ids, item.ID.VideoID)
+			}
+		}
+		if page.NextPageToken == "" || len(page.Items) == 0 {
+			break
+		}
+		pageToken = page.NextPageToken
+	}
+	return ids, nil
+}
+
+// videosFromIDs loads the details of each video ID, recording the ones that cannot be loaded
+func (s *BucketService) videosFromIDs(
+	ctx context.Context,
+	client YouTubeClient,
+	ids []string,
+	kind string,
+	result *YouTubeImportResult,
+	progress func(YouTubeImportProgress),
+) []*youtube.Video {
+	videos := make([]*youtube.Video, 0, len(ids))
+	for _, id := range ids {
+		video, err := client.GetVideoContext(ctx, id)
+		if err != nil {
+			s.recordUnresolvedVideo(kind, "", id, err, result, progress)
+			continue
+		}
+		videos = append(videos, video)
+	}
+	return videos
+}
+
+// youtubeAPIGet calls a YouTube Data API endpoint with the configured key and decodes the
+// JSON response into out
+func (s *BucketService) youtubeAPIGet(ctx context.Context, endpoint string, params url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, youtubeDataAPIBaseURL+endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	// The key goes in a header: transport errors (*url.Error) include the request URL
+	req.Header.Set("X-Goog-Api-Key", s.youtubeAPIKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("youtube data api %s: unexpected status %d", endpoint, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// youtubeVideoLikeCount loads the like count of a video from the YouTube Data API. ok is false
+// when the creator hides the count.
+func (s *BucketService) youtubeVideoLikeCount(ctx context.Context, videoID string) (count int64, ok bool, err error) {
+	if s.youtubeAPIKey == "" {
+		return 0, false, ErrYouTubeAPIKeyMissing
+	}
+
+	var response struct {
+		Items []struct {
+			Statistics struct {
+				LikeCount string `json:"likeCount"`
+			} `json:"statistics"`
+		} `json:"items"`
+	}
+	params := url.Values{"part": {"statistics"}, "id": {videoID}}
+	if err := s.youtubeAPIGet(ctx, "videos", params, &response); err != nil {
+		return 0, false, err
+	}
+	if len(response.Items) == 0 {
+		return 0, false, fmt.Errorf("video %s not found", videoID)
+	}
+	if response.Items[0].Statistics.LikeCount == "" {
+		return 0, false, nil
+	}
+	count, err = strconv.ParseInt(response.Items[0].Statistics.LikeCount, 10, 64)
+	return count, err == nil, err
+}
+
+// youtubeVideoTags loads the tags (keywords) the creator set on a video from the YouTube Data
+// API, which the player response used for imports doesn't include
+func (s *BucketService) youtubeVideoTags(ctx context.Context, videoID string) ([]string, error) {
+	if s.youtubeAPIKey == "" {
+		return nil, ErrYouTubeAPIKeyMissing
+	}
+
+	var response struct {
+		Items []struct {
+			Snippet struct {
+				Tags []string `json:"tags"`
+			} `json:"snippet"`
+		} `json:"items"`
+	}
+	params := url.Values{"part": {"snippet"}, "id": {videoID}}
+	if err := s.youtubeAPIGet(ctx, "videos", params, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Items) == 0 {
+		return nil, fmt.Errorf("video %s not found", videoID)
+	}
+	return response.Items[0].Snippet.Tags, nil
+}
+
+// PlaylistInfo describes a playlist of a YouTube channel
+type PlaylistInfo struct {
+	ID         string `json:"id"`
+	Title      string `json:"title"`
+	VideoCount int    `json:"videoCount"`
+}
+
+// ListYouTubePlaylists lists the playlists of the channel behind channelURL, such as
+// https://www.youtube.com/@handle/playlists or https://www.youtube.com/channel/<id>. A playlist
+// ID can then be imported with ImportYouTube. It needs a YouTube Data API key.
+func (s *BucketService) ListYouTubePlaylists(ctx context.Context, channelURL string) ([]PlaylistInfo, error) {
+	if s.youtubeAPIKey == "" {
+		return nil, ErrYouTubeAPIKeyMissing
+	}
+
+	channelID, err := s.resolveYouTubeChannelID(ctx, channelURL)
+	if err != nil {
+		return nil, err
+	}
+
+	playlists := make([]PlaylistInfo, 0)
+	pageToken := ""
+	for {
+		params := url.Values{}
+		params.Set("part", "snippet,contentDetails")
+		params.Set("channelId", channelID)
+		params.Set("maxResults", strconv.Itoa(youtubeSearchPageSize))
+		if pageToken != "" {
+			params.Set("pageToken", pageToken)
+		}
+
+		var page struct {
+			NextPageToken string `json:"nextPageToken"`
+			Items         []struct {
+				ID      string `json:"id"`
+				Snippet struct {
+					Title string `json:"title"`
+				} `json:"snippet"`
+				ContentDetails struct {
+					ItemCount int `json:"itemCount"`
+				} `json:"contentDetails"`
+			} `json:"items"`
+		}
+		if err := s.youtubeAPIGet(ctx, "playlists", params, &page); err != nil {
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			playlists = append(playlists, PlaylistInfo{
+				ID:         item.ID,
+				Title:      item.Snippet.Title,
+				VideoCount: item.ContentDetails.ItemCount,
+			})
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		pageToken = page.NextPageToken
+	}
+
+	return playlists, nil
+}
+
+// resolveYouTubeChannelID extracts the channel ID from a channel URL, looking handles up
+// through the Data API
+func (s *BucketService) resolveYouTubeChannelID(ctx context.Context, channelURL string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(channelURL))
+	if err != nil {
+		return "", fmt.Errorf("invalid channel url: %w", err)
+	}
+
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if len(segments) >= 2 && segments[0] == "channel" && segments[1] != "" {
+		return segments[1], nil
+	}
+	if len(segments) == 0 || !strings.HasPrefix(segments[0], "@") || len(segments[0]) < 2 {
+		return "", fmt.Errorf("invalid channel url: expected /@handle or /channel/<id>")
+	}
+
+	params := url.Values{}
+	params.Set("part", "id")
+	params.Set("forHandle", segments[0])
+
+	var channels struct {
+		Items []struct {
+			ID string `json:"id"`
+		} `json:"items"`
+	}
+	if err := s.youtubeAPIGet(ctx, "channels", params, &channels); err != nil {
+		return "", err
+	}
+	if len(channels.Items) == 0 {
+		return "", fmt.Errorf("youtube channel %s not found", segments[0])
+	}
+	return channels.Items[0].ID, nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/kkdai/youtube/v2"
+)
+
+// defaultSubtitleLanguage is burned in when BurnSubtitles is set without a SubtitleLanguage
+const defaultSubtitleLanguage = "en"
+
+// ffmpegInstallHint is appended to ErrFFmpegNotFound
+const ffmpegInstallHint = "install ffmpeg (e.g. apt install ffmpeg, brew install ffmpeg or https://ffmpeg.org/download.html) " +
+	"and make sure it is on the PATH, or configure its location with WithFFmpegPath"
+
+// checkFFmpeg returns ErrFFmpegNotFound when the configured ffmpeg binary cannot be found
+func (s *BucketService) checkFFmpeg() error {
+	ffmpegPath := s.ffmpegPath
+	if ffmpegPath == "" {
+		ffmpegPath = defaultFFmpegPath
+	}
+	if _, err := exec.LookPath(ffmpegPath); err != nil {
+		return fmt.Errorf("%w: %s", ErrFFmpegNotFound, ffmpegInstallHint)
+	}
+	return nil
+}
+
+// burnedSubtitleFormat describes the file produced by burning subtitles into format. The video
+// is re-encoded, so the size is unknown until the upload finishes.
+func burnedSubtitleFormat(format *youtube.Format) *youtube.Format {
+	burned := *format
+	burned.MimeType = "video/mp4"
+	burned.ContentLength = 0
+	return &burned
+}
+
+// selectCaptionTrack returns the caption track for language, preferring tracks written by the
+// creator over automatic ones. A language without region (e.g. "en") also matches regional
+// tracks such as "en-GB".
+func selectCaptionTrack(video *youtube.Video, language string) *youtube.CaptionTrack {
+	var best *youtube.CaptionTrack
+	bestRank := 0
+	for i := range video.CaptionTracks {
+		track := &video.CaptionTracks[i]
+		rank := 0
+		switch {
+		case strings.EqualFold(track.LanguageCode, language):
+			rank = 2
+		case strings.HasPrefix(strings.ToLower(track.LanguageCode), strings.ToLower(language)+"-"):
+			rank = 1
+		default:
+			continue
+		}
+		if track.Kind != "asr" {
+			rank += 2
+		}
+		if rank > bestRank {
+			best, bestRank = track, rank
+		}
+	}
+	return best
+}
+
+// downloadYouTubeSubtitles downloads the video's subtitles for the import's language to a
+// temporary file and returns its path
+func downloadYouTubeSubtitles(ctx context.Context, client YouTubeClient, video *youtube.Video, input YouTubeImportInput) (string, error) {
+	language := input.SubtitleLanguage
+	if language == "" {
+		language = defaultSubtitleLanguage
+	}
+	track := selectCaptionTrack(video, language)
+	if track == nil {
+		return "", fmt.Errorf("video has no %q subtitles", language)
+	}
+
+	subtitlePath, err := downloadCaptionTrack(ctx, client, track)
+	if err != nil {
+		return "", fmt.Errorf("download subtitles: %w", err)
+	}
+	return subtitlePath, nil
+}
+
+// burnYouTubeSubtitles renders the subtitles downloaded to subtitlePath into stream. The file is
+// removed once ffmpeg is done with it.
+func (s *BucketService) burnYouTubeSubtitles(ctx context.Context, stream io.ReadCloser, subtitlePath string) (io.ReadCloser, error) {
+	args := []string{
+		"-i", "pipe:3",
+		"-vf", "subtitles=" + ffmpegFilterPath(subtitlePath),
+		"-c:v", "libx264", "-preset", "veryfast",
+		"-c:a", "aac",
+		"-movflags", "frag_keyframe+empty_moov", "-f", "mp4",
+	}
+	burned, err := startFFmpeg(ctx, s.ffmpegPath, args, stream)
+	if err != nil {
+		os.Remove(subtitlePath)
+		return nil, err
+	}
+	burned.cleanup = func() { os.Remove(subtitlePath) }
+	return burned, nil
+}
+
+// downloadCaptionTrack stores the track as a WebVTT file in the temporary directory, since the
+// ffmpeg subtitles filter only reads files. The caller removes the file.
+func downloadCaptionTrack(ctx context.Context, client YouTubeClient, track *youtube.CaptionTrack) (string, error) {
+	httpClient := http.DefaultClient
+	if ytClient, ok := client.(*youtube.Client); ok && ytClient.HTTPClient != nil {
+		httpClient = ytClient.HTTPClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, track.BaseURL+"&fmt=vtt", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	file, err := os.CreateTemp("", "bucketbird-subtitles-*.vtt")
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// ffmpegFilterPath escapes the characters of a path that are special in a filter option value
+func ffmpegFilterPath(path string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `'`, `\'`, `:`, `\:`, `,`, `\,`, `;`, `\;`, `[`, `\[`, `]`, `\]`)
+	return replacer.Replace(filepath.ToSlash(path))
+}
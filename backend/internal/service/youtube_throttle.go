@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/kkdai/youtube/v2"
+)
+
+// requestPacer spaces requests at least interval apart. It is shared by all imports of the
+// service, so concurrent imports don't multiply the request rate.
+type requestPacer struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRequestPacer(requestsPerSecond float64) *requestPacer {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+	return &requestPacer{interval: time.Duration(float64(time.Second) / requestsPerSecond)}
+}
+
+// wait blocks until the next request may be sent, or until ctx is done
+func (p *requestPacer) wait(ctx context.Context) error {
+	p.mu.Lock()
+	now := time.Now()
+	at := p.next
+	if at.Before(now) {
+		at = now
+	}
+	p.next = at.Add(p.interval)
+	p.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// pacedYouTubeClient waits for the pacer before every call of the wrapped client. Opening a
+// stream counts as one request, however many chunk requests the client makes to read it.
+type pacedYouTubeClient struct {
+	client YouTubeClient
+	pacer  *requestPacer
+}
+
+// pacedYouTubeClient returns client limited to the service's WithRequestsPerSecond rate
+func (s *BucketService) pacedYouTubeClient(client YouTubeClient) YouTubeClient {
+	if s.youtubePacer == nil {
+		return client
+	}
+	return &pacedYouTubeClient{client: client, pacer: s.youtubePacer}
+}
+
+func (c *pacedYouTubeClient) GetPlaylistContext(ctx context.Context, url string) (*youtube.Playlist, error) {
+	if err := c.pacer.wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.client.GetPlaylistContext(ctx, url)
+}
+
+func (c *pacedYouTubeClient) GetVideoContext(ctx context.Context, url string) (*youtube.Video, error) {
+	if err := c.pacer.wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.client.GetVideoContext(ctx, url)
+}
+
+func (c *pacedYouTubeClient) VideoFromPlaylistEntryContext(ctx context.Context, entry *youtube.PlaylistEntry) (*youtube.Video, error) {
+	if err := c.pacer.wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.client.VideoFromPlaylistEntryContext(ctx, entry)
+}
+
+func (c *pacedYouTubeClient) GetStreamContext(ctx context.Context, video *youtube.Video, format *youtube.Format) (io.ReadCloser, int64, error) {
+	if err := c.pacer.wait(ctx); err != nil {
+		return nil, 0, err
+	}
+	return c.client.GetStreamContext(ctx, video, format)
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"bucketbird/backend/internal/storage"
+)
+
+// ValidationError describes an invalid YouTubeImportInput field
+type ValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+
+	// err is a more specific sentinel error the field violates, if any
+	err error
+}
+
+func (e ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (e ValidationError) Unwrap() error {
+	return e.err
+}
+
+// youtubeQualityPattern matches the quality labels (e.g. "720p", "1080p60") and qualities (e.g.
+// "hd720") YouTube reports for its formats
+var youtubeQualityPattern = regexp.MustCompile(`(?i)^(\d{3,4}p(\d{2})?|tiny|small|medium|large|hd720|hd1080|hd1440|hd2160|highres)$`)
+
+// Validate checks the input without making any network call. Zero values select the defaults,
+// so a Concurrency of 0 is valid and means the service's default concurrency.
+func (in *YouTubeImportInput) Validate() []ValidationError {
+	var errs []ValidationError
+	invalid := func(field, message string, err error) {
+		errs = append(errs, ValidationError{Field: field, Message: message, err: err})
+	}
+
+	if strings.TrimSpace(in.URL) == "" && in.searchVideoIDs == nil {
+		invalid("URL", "is required", nil)
+	}
+	if _, err := normalizeObjectPrefix(in.DestinationPrefix); err != nil {
+		invalid("DestinationPrefix", "must not contain .. segments", err)
+	}
+	if in.Concurrency < 0 || in.Concurrency > maxImportConcurrency {
+		invalid("Concurrency", fmt.Sprintf("must be between 0 and %d", maxImportConcurrency), nil)
+	}
+	if quality := strings.TrimSpace(in.Quality); quality != "" && !youtubeQualityPattern.MatchString(quality) {
+		invalid("Quality", fmt.Sprintf("%q is not a YouTube quality such as 720p or hd720", quality), nil)
+	}
+	if in.BandwidthLimit < 0 {
+		invalid("BandwidthLimit", "must not be negative", nil)
+	}
+	if in.StallTimeoutSeconds < 0 {
+		invalid("StallTimeoutSeconds", "must not be negative", nil)
+	}
+	if in.MaxFilenameLengthBytes < 0 {
+		invalid("MaxFilenameLengthBytes", "must not be negative", nil)
+	}
+	if in.MaxBitrateKbps < 0 {
+		invalid("MaxBitrateKbps", "must not be negative", nil)
+	}
+	if in.LiveStreamPollIntervalSeconds < 0 {
+		invalid("LiveStreamPollIntervalSeconds", "must not be negative", nil)
+	}
+	if in.MultipartChunkSizeMB != 0 && (in.MultipartChunkSizeMB < minMultipartChunkSizeMB || in.MultipartChunkSizeMB > maxMultipartChunkSizeMB) {
+		invalid("MultipartChunkSizeMB", fmt.Sprintf("must be between %d and %d", minMultipartChunkSizeMB, maxMultipartChunkSizeMB), nil)
+	}
+	switch in.ServerSideEncryption {
+	case "", storage.ServerSideEncryptionS3, storage.ServerSideEncryptionKMS:
+	default:
+		invalid("ServerSideEncryption", fmt.Sprintf("unknown mode %q, use %q or %q", in.ServerSideEncryption, storage.ServerSideEncryptionS3, storage.ServerSideEncryptionKMS), nil)
+	}
+	if in.SSEKMSKeyID != "" && in.ServerSideEncryption != storage.ServerSideEncryptionKMS {
+		invalid("SSEKMSKeyID", fmt.Sprintf("requires ServerSideEncryption %q", storage.ServerSideEncryptionKMS), nil)
+	}
+	if in.ObjectLockEnabled {
+		switch in.ObjectLockMode {
+		case storage.ObjectLockGovernance, storage.ObjectLockCompliance:
+		default:
+			invalid("ObjectLockMode", fmt.Sprintf("must be %q or %q", storage.ObjectLockGovernance, storage.ObjectLockCompliance), nil)
+		}
+		if !in.ObjectLockRetainUntil.After(time.Now()) {
+			invalid("ObjectLockRetainUntil", "must be in the future", nil)
+		}
+	}
+	if len(in.ImportNote) > maxImportNoteBytes {
+		invalid("ImportNote", fmt.Sprintf("must not be longer than %d bytes", maxImportNoteBytes), nil)
+	}
+	switch in.OverwritePolicy {
+	case "", OverwriteSkipExisting, OverwriteReplaceExisting:
+	default:
+		invalid("OverwritePolicy", fmt.Sprintf("unknown policy %q", in.OverwritePolicy), nil)
+	}
+	switch in.SanitizeMode {
+	case "", SanitizeModeASCII, SanitizeModeUnicode:
+	default:
+		invalid("SanitizeMode", fmt.Sprintf("unknown mode %q", in.SanitizeMode), nil)
+	}
+	if (in.PoToken == "") != (in.VisitorData == "") {
+		invalid("PoToken", "must be set together with VisitorData", ErrIncompletePoToken)
+	}
+
+	return errs
+}
+
+// validationError combines the errors of Validate into one error matching ErrInvalidInput
+func validationError(errs []ValidationError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	joined := make([]error, len(errs))
+	for i, err := range errs {
+		joined[i] = err
+	}
+	return fmt.Errorf("%w: %w", ErrInvalidInput, errors.Join(joined...))
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	importWebhookAttempts    = 3
+	importWebhookBaseBackoff = time.Second
+	importWebhookTimeout     = 10 * time.Second
+)
+
+// importWebhookSignatureHeader carries the HMAC-SHA256 of the body as "sha256=<hex>"
+const importWebhookSignatureHeader = "X-BucketBird-Signature"
+
+type importWebhook struct {
+	mu     sync.RWMutex
+	url    string
+	secret string
+}
+
+// SetImportWebhook makes every ImportYouTube call POST its result as JSON to url. The body is
+// signed with HMAC-SHA256 using secret, see importWebhookSignatureHeader. An empty url disables
+// the webhook.
+func (s *BucketService) SetImportWebhook(url, secret string) {
+	s.webhook.mu.Lock()
+	defer s.webhook.mu.Unlock()
+	s.webhook.url = url
+	s.webhook.secret = secret
+}
+
+// notifyImportWebhook delivers result to the configured webhook in the background
+func (s *BucketService) notifyImportWebhook(result *YouTubeImportResult) {
+	s.webhook.mu.RLock()
+	url, secret := s.webhook.url, s.webhook.secret
+	s.webhook.mu.RUnlock()
+	if url == "" {
+		return
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		s.logger.Error("failed to encode import webhook payload", "error", err)
+		return
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	go func() {
+		if err := s.deliverImportWebhook(context.Background(), url, body, signature); err != nil {
+			s.logger.Error("failed to deliver import webhook", "url", url, "error", err)
+		}
+	}()
+}
+
+// deliverImportWebhook posts body, retrying failed attempts with exponential backoff
+func (s *BucketService) deliverImportWebhook(ctx context.Context, url string, body []byte, signature string) error {
+	client := &http.Client{Timeout: importWebhookTimeout}
+
+	var lastErr error
+	backoff := importWebhookBaseBackoff
+	for attempt := 1; attempt <= importWebhookAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(importWebhookSignatureHeader, signature)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			s.logger.Debug("import webhook delivery failed", "url", url, "attempt", attempt, "error", err)
+			continue
+		}
+		resp.Body.Close()
+
+		s.logger.Debug("import webhook delivered", "url", url, "attempt", attempt, "status", resp.StatusCode)
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		lastErr = fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return fmt.Errorf("giving up after %d attempts: %w", importWebhookAttempts, lastErr)
+}
@@ -2,7 +2,9 @@ package storage
 
 import (
 	"bytes"
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,6 +13,7 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	awsv2 "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -23,6 +26,161 @@ type ObjectStore struct {
 	bucketNamingPrefix string
 }
 
+// ObjectStoreClient is the set of object operations the services rely on. *ObjectStore
+// implements it against S3-compatible backends; tests can substitute an in-memory store.
+type ObjectStoreClient interface {
+	ListObjects(ctx context.Context, bucket string, prefix string) ([]types.Object, error)
+	ListAllObjects(ctx context.Context, bucket, prefix string) ([]types.Object, error)
+	HeadObject(ctx context.Context, bucket, key string) (*s3.HeadObjectOutput, error)
+	GetObject(ctx context.Context, bucket, key string) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, bucket, key string, body io.Reader, contentType string, metadata map[string]string, opts ...ObjectOption) (string, error)
+	PutEmptyObject(ctx context.Context, bucket, key string, contentType *string) error
+	CopyObject(ctx context.Context, bucket, sourceKey, destinationKey string) error
+	ReplaceObjectMetadata(ctx context.Context, bucket, key, contentType string, metadata map[string]string, opts ...ObjectOption) error
+	PutObjectTagging(ctx context.Context, bucket, key string, tags map[string]string) error
+	DeleteObject(ctx context.Context, bucket, key string) error
+	DeleteObjects(ctx context.Context, bucket string, keys []string) error
+	DeleteBucket(ctx context.Context, name string) error
+	PresignObject(ctx context.Context, input PresignInput) (PresignOutput, error)
+	CalculateBucketSize(ctx context.Context, bucket string) (int64, error)
+	CalculatePrefixSize(ctx context.Context, bucket, prefix string) (int64, error)
+}
+
+var _ ObjectStoreClient = (*ObjectStore)(nil)
+
+// ObjectOptions are optional HTTP headers stored with an object and returned when it is fetched
+type ObjectOptions struct {
+	ContentDisposition string
+	CacheControl       string
+	// ServerSideEncryption is "AES256" for SSE-S3 or "aws:kms" for SSE-KMS, in which case
+	// SSEKMSKeyID optionally selects a key other than the account's default one
+	ServerSideEncryption string
+	SSEKMSKeyID          string
+	// ObjectLockMode ("GOVERNANCE" or "COMPLIANCE") and ObjectLockRetainUntil make the object
+	// immutable until the given time. The bucket must have object lock enabled.
+	ObjectLockMode        string
+	ObjectLockRetainUntil time.Time
+	// PartSize > 0 uploads bodies larger than one part as a multipart upload, calling OnPart
+	// after each part is stored
+	PartSize int64
+	OnPart   func(partNumber int)
+}
+
+// ObjectOption sets one of the ObjectOptions
+type ObjectOption func(*ObjectOptions)
+
+// WithContentDisposition stores a Content-Disposition header, e.g. "attachment" to make
+// browsers download the object instead of displaying it
+func WithContentDisposition(value string) ObjectOption {
+	return func(o *ObjectOptions) {
+		o.ContentDisposition = value
+	}
+}
+
+// WithCacheControl stores a Cache-Control header, which CDNs in front of the bucket pass on
+func WithCacheControl(value string) ObjectOption {
+	return func(o *ObjectOptions) {
+		o.CacheControl = value
+	}
+}
+
+// Server-side encryption modes
+const (
+	ServerSideEncryptionS3  = string(types.ServerSideEncryptionAes256)
+	ServerSideEncryptionKMS = string(types.ServerSideEncryptionAwsKms)
+)
+
+// WithServerSideEncryption has the storage backend encrypt the object at rest. kmsKeyID is
+// only used with ServerSideEncryptionKMS.
+func WithServerSideEncryption(mode, kmsKeyID string) ObjectOption {
+	return func(o *ObjectOptions) {
+		o.ServerSideEncryption = mode
+		o.SSEKMSKeyID = kmsKeyID
+	}
+}
+
+// Object lock modes
+const (
+	ObjectLockGovernance = string(types.ObjectLockModeGovernance)
+	ObjectLockCompliance = string(types.ObjectLockModeCompliance)
+)
+
+// WithObjectLock retains the object in the given mode until retainUntil
+func WithObjectLock(mode string, retainUntil time.Time) ObjectOption {
+	return func(o *ObjectOptions) {
+		o.ObjectLockMode = mode
+		o.ObjectLockRetainUntil = retainUntil
+	}
+}
+
+// WithMultipartUpload splits bodies larger than partSize bytes into parts of that size. S3
+// requires parts of at least 5 MiB, except for the last one.
+func WithMultipartUpload(partSize int64, onPart func(partNumber int)) ObjectOption {
+	return func(o *ObjectOptions) {
+		o.PartSize = partSize
+		o.OnPart = onPart
+	}
+}
+
+// ObjectOptionsFromHead returns the options an existing object was stored with, so a copy of it
+// onto itself, such as ReplaceObjectMetadata, keeps them. An expired retention is not carried
+// over, since S3 rejects retention dates in the past.
+func ObjectOptionsFromHead(head *s3.HeadObjectOutput) []ObjectOption {
+	var opts []ObjectOption
+	if head == nil {
+		return opts
+	}
+	if v := aws.ToString(head.ContentDisposition); v != "" {
+		opts = append(opts, WithContentDisposition(v))
+	}
+	if v := aws.ToString(head.CacheControl); v != "" {
+		opts = append(opts, WithCacheControl(v))
+	}
+	if head.ServerSideEncryption != "" {
+		opts = append(opts, WithServerSideEncryption(string(head.ServerSideEncryption), aws.ToString(head.SSEKMSKeyId)))
+	}
+	if head.ObjectLockMode != "" && head.ObjectLockRetainUntilDate != nil && head.ObjectLockRetainUntilDate.After(time.Now()) {
+		opts = append(opts, WithObjectLock(string(head.ObjectLockMode), *head.ObjectLockRetainUntilDate))
+	}
+	return opts
+}
+
+func applyObjectOptions(opts []ObjectOption) ObjectOptions {
+	var options ObjectOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// storageFullErrorCodes are the error codes S3-compatible backends use when a write is rejected
+// because the bucket or disk has no space left
+var storageFullErrorCodes = map[string]bool{
+	"QuotaExceeded":                  true,
+	"StorageFull":                    true,
+	"XMinioStorageFull":              true,
+	"XMinioAdminBucketQuotaExceeded": true,
+}
+
+// IsStorageFull reports whether err means the backend refused a write for lack of space
+func IsStorageFull(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var apiErr interface{ ErrorCode() string }
+	if errors.As(err, &apiErr) && storageFullErrorCodes[apiErr.ErrorCode()] {
+		return true
+	}
+
+	var responseErr *awshttp.ResponseError
+	if errors.As(err, &responseErr) {
+		return responseErr.HTTPStatusCode() == http.StatusInsufficientStorage
+	}
+
+	return false
+}
+
 type ObjectStoreConfig struct {
 	Endpoint  string
 	Region    string
@@ -31,6 +189,30 @@ type ObjectStoreConfig struct {
 	UseSSL    bool
 }
 
+// IsNotFound reports whether err is a typed "object does not exist" error returned by the S3 client
+func IsNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return true
+	}
+
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return true
+	}
+
+	var responseErr *awshttp.ResponseError
+	if errors.As(err, &responseErr) {
+		return responseErr.HTTPStatusCode() == http.StatusNotFound
+	}
+
+	return false
+}
+
 func NewObjectStore(ctx context.Context, cfg ObjectStoreConfig) (*ObjectStore, error) {
 	endpoint := strings.TrimSpace(cfg.Endpoint)
 	if endpoint == "" {
@@ -220,6 +402,14 @@ func (o *ObjectStore) PutEmptyObject(ctx context.Context, bucket, key string, co
 	return err
 }
 
+func (o *ObjectStore) DeleteObject(ctx context.Context, bucket, key string) error {
+	_, err := o.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
 func (o *ObjectStore) DeleteObjects(ctx context.Context, bucket string, keys []string) error {
 	if len(keys) == 0 {
 		return nil
@@ -260,7 +450,8 @@ func (o *ObjectStore) GetObject(ctx context.Context, bucket, key string) (*s3.Ge
 	})
 }
 
-func (o *ObjectStore) PutObject(ctx context.Context, bucket, key string, body io.Reader, contentType string, metadata map[string]string) error {
+// PutObject uploads an object and returns its ETag without surrounding quotes
+func (o *ObjectStore) PutObject(ctx context.Context, bucket, key string, body io.Reader, contentType string, metadata map[string]string, opts ...ObjectOption) (string, error) {
 	input := &s3.PutObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
@@ -272,11 +463,186 @@ func (o *ObjectStore) PutObject(ctx context.Context, bucket, key string, body io
 	if len(metadata) > 0 {
 		input.Metadata = metadata
 	}
+	options := applyObjectOptions(opts)
+	if options.ContentDisposition != "" {
+		input.ContentDisposition = aws.String(options.ContentDisposition)
+	}
+	if options.CacheControl != "" {
+		input.CacheControl = aws.String(options.CacheControl)
+	}
+	if options.ServerSideEncryption != "" {
+		input.ServerSideEncryption = types.ServerSideEncryption(options.ServerSideEncryption)
+		if options.ServerSideEncryption == ServerSideEncryptionKMS && options.SSEKMSKeyID != "" {
+			input.SSEKMSKeyId = aws.String(options.SSEKMSKeyID)
+		}
+	}
+	if options.ObjectLockMode != "" {
+		input.ObjectLockMode = types.ObjectLockMode(options.ObjectLockMode)
+		input.ObjectLockRetainUntilDate = aws.Time(options.ObjectLockRetainUntil)
+		// S3 only accepts locked objects with an integrity checksum
+		input.ChecksumAlgorithm = types.ChecksumAlgorithmCrc32
+	}
+	if options.PartSize > 0 {
+		return o.putObjectMultipart(ctx, input, options)
+	}
 
-	_, err := o.client.PutObject(ctx, input)
+	return o.putObject(ctx, input)
+}
+
+func (o *ObjectStore) putObject(ctx context.Context, input *s3.PutObjectInput) (string, error) {
+	out, err := o.client.PutObject(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	etag := ""
+	if out.ETag != nil {
+		etag = strings.Trim(*out.ETag, "\"")
+	}
+	return etag, nil
+}
+
+// putObjectMultipart uploads input.Body in parts of options.PartSize. A body that fits into
+// one part is stored with a plain PutObject instead. The body is copied with io.Copy, so a
+// body implementing io.WriterTo writes straight into the parts.
+func (o *ObjectStore) putObjectMultipart(ctx context.Context, input *s3.PutObjectInput, options ObjectOptions) (string, error) {
+	w := &multipartWriter{ctx: ctx, store: o, input: input, options: options}
+	if _, err := io.Copy(w, input.Body); err != nil {
+		return "", w.abort(err)
+	}
+	etag, err := w.finish()
+	if err != nil {
+		return "", w.abort(err)
+	}
+	return etag, nil
+}
+
+// multipartWriter buffers the bytes written to it into parts. The multipart upload is only
+// created once a second part starts, and the buffer grows as data arrives instead of being
+// allocated at the full part size.
+type multipartWriter struct {
+	ctx     context.Context
+	store   *ObjectStore
+	input   *s3.PutObjectInput
+	options ObjectOptions
+
+	buf      []byte
+	uploadID *string
+	parts    []types.CompletedPart
+}
+
+func (w *multipartWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if int64(len(w.buf)) == w.options.PartSize {
+			if err := w.uploadPart(); err != nil {
+				return written, err
+			}
+		}
+		n := min(len(p), int(w.options.PartSize)-len(w.buf))
+		if len(w.buf)+n > cap(w.buf) {
+			grown := make([]byte, len(w.buf), min(max(2*cap(w.buf), len(w.buf)+n), int(w.options.PartSize)))
+			copy(grown, w.buf)
+			w.buf = grown
+		}
+		w.buf = append(w.buf, p[:n]...)
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// uploadPart uploads the buffered bytes as the next part, creating the upload first if needed
+func (w *multipartWriter) uploadPart() error {
+	input := w.input
+	if w.uploadID == nil {
+		created, err := w.store.client.CreateMultipartUpload(w.ctx, &s3.CreateMultipartUploadInput{
+			Bucket:                    input.Bucket,
+			Key:                       input.Key,
+			ContentType:               input.ContentType,
+			Metadata:                  input.Metadata,
+			ContentDisposition:        input.ContentDisposition,
+			CacheControl:              input.CacheControl,
+			ServerSideEncryption:      input.ServerSideEncryption,
+			SSEKMSKeyId:               input.SSEKMSKeyId,
+			ObjectLockMode:            input.ObjectLockMode,
+			ObjectLockRetainUntilDate: input.ObjectLockRetainUntilDate,
+			ChecksumAlgorithm:         input.ChecksumAlgorithm,
+		})
+		if err != nil {
+			return err
+		}
+		w.uploadID = created.UploadId
+	}
+
+	partNumber := int32(len(w.parts) + 1)
+	out, err := w.store.client.UploadPart(w.ctx, &s3.UploadPartInput{
+		Bucket:            input.Bucket,
+		Key:               input.Key,
+		UploadId:          w.uploadID,
+		PartNumber:        aws.Int32(partNumber),
+		Body:              bytes.NewReader(w.buf),
+		ChecksumAlgorithm: input.ChecksumAlgorithm,
+	})
+	if err != nil {
+		return fmt.Errorf("upload part %d: %w", partNumber, err)
+	}
+	w.parts = append(w.parts, types.CompletedPart{ETag: out.ETag, PartNumber: aws.Int32(partNumber), ChecksumCRC32: out.ChecksumCRC32})
+	if w.options.OnPart != nil {
+		w.options.OnPart(int(partNumber))
+	}
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// finish stores a body that fit into one part with PutObject, or uploads the last part and
+// completes the upload
+func (w *multipartWriter) finish() (string, error) {
+	if w.uploadID == nil {
+		w.input.Body = bytes.NewReader(w.buf)
+		return w.store.putObject(w.ctx, w.input)
+	}
+	if len(w.buf) > 0 {
+		if err := w.uploadPart(); err != nil {
+			return "", err
+		}
+	}
+
+	out, err := w.store.client.CompleteMultipartUpload(w.ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          w.input.Bucket,
+		Key:             w.input.Key,
+		UploadId:        w.uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: w.parts},
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.Trim(aws.ToString(out.ETag), "\""), nil
+}
+
+// abort cancels a started upload after err. Stored parts are billed until the upload is
+// aborted, even when ctx is already done.
+func (w *multipartWriter) abort(err error) error {
+	if w.uploadID == nil {
+		return err
+	}
+	if _, abortErr := w.store.client.AbortMultipartUpload(context.WithoutCancel(w.ctx), &s3.AbortMultipartUploadInput{
+		Bucket:   w.input.Bucket,
+		Key:      w.input.Key,
+		UploadId: w.uploadID,
+	}); abortErr != nil {
+		return errors.Join(err, fmt.Errorf("abort multipart upload: %w", abortErr))
+	}
 	return err
 }
 
+// maxCopyObjectSize is the largest object S3 copies in a single CopyObject request
+const maxCopyObjectSize = 5 * 1024 * 1024 * 1024
+
+// copyPartSize is the part size used to copy larger objects
+const copyPartSize = 512 * 1024 * 1024
+
+// CopyObject copies an object within the bucket. Objects larger than 5 GiB, which S3 refuses to
+// copy in one request, are copied in parts.
 func (o *ObjectStore) CopyObject(ctx context.Context, bucket, sourceKey, destinationKey string) error {
 	escapedKey := strings.ReplaceAll(url.PathEscape(sourceKey), "%2F", "/")
 	copySource := fmt.Sprintf("%s/%s", bucket, escapedKey)
@@ -285,6 +651,160 @@ func (o *ObjectStore) CopyObject(ctx context.Context, bucket, sourceKey, destina
 		CopySource: aws.String(copySource),
 		Key:        aws.String(destinationKey),
 	})
+	if err == nil {
+		return nil
+	}
+
+	// Only look at the size once the copy failed, so regular copies don't pay for a HeadObject
+	head, headErr := o.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(sourceKey),
+	})
+	if headErr != nil || aws.ToInt64(head.ContentLength) <= maxCopyObjectSize {
+		return err
+	}
+	// Keep the content type, metadata, headers and encryption of the source
+	return o.copyObjectMultipart(ctx, copySource, aws.ToInt64(head.ContentLength), &s3.CreateMultipartUploadInput{
+		Bucket:               aws.String(bucket),
+		Key:                  aws.String(destinationKey),
+		ContentType:          head.ContentType,
+		Metadata:             head.Metadata,
+		ContentDisposition:   head.ContentDisposition,
+		CacheControl:         head.CacheControl,
+		ContentEncoding:      head.ContentEncoding,
+		ServerSideEncryption: head.ServerSideEncryption,
+		SSEKMSKeyId:          head.SSEKMSKeyId,
+	})
+}
+
+// copyObjectMultipart copies size bytes of copySource into the upload described by create, in
+// parts of copyPartSize
+func (o *ObjectStore) copyObjectMultipart(ctx context.Context, copySource string, size int64, create *s3.CreateMultipartUploadInput) error {
+	bucket, destinationKey := aws.ToString(create.Bucket), aws.ToString(create.Key)
+	created, err := o.client.CreateMultipartUpload(ctx, create)
+	if err != nil {
+		return err
+	}
+
+	var parts []types.CompletedPart
+	copyParts := func() error {
+		for start := int64(0); start < size; start += copyPartSize {
+			end := min(start+copyPartSize, size) - 1
+			partNumber := int32(len(parts) + 1)
+			out, err := o.client.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+				Bucket:          aws.String(bucket),
+				Key:             aws.String(destinationKey),
+				UploadId:        created.UploadId,
+				PartNumber:      aws.Int32(partNumber),
+				CopySource:      aws.String(copySource),
+				CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+			})
+			if err != nil {
+				return fmt.Errorf("copy part %d: %w", partNumber, err)
+			}
+			part := types.CompletedPart{PartNumber: aws.Int32(partNumber)}
+			if out.CopyPartResult != nil {
+				part.ETag = out.CopyPartResult.ETag
+				part.ChecksumCRC32 = out.CopyPartResult.ChecksumCRC32
+			}
+			parts = append(parts, part)
+		}
+		return nil
+	}
+	if err := copyParts(); err != nil {
+		if _, abortErr := o.client.AbortMultipartUpload(context.WithoutCancel(ctx), &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(bucket),
+			Key:      aws.String(destinationKey),
+			UploadId: created.UploadId,
+		}); abortErr != nil {
+			return errors.Join(err, fmt.Errorf("abort multipart copy: %w", abortErr))
+		}
+		return err
+	}
+
+	_, err = o.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucket),
+		Key:             aws.String(destinationKey),
+		UploadId:        created.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	return err
+}
+
+// ReplaceObjectMetadata rewrites the user metadata of an existing object by copying it onto itself.
+// The copy also replaces the optional headers, so the ones to keep must be passed again. Objects
+// larger than 5 GiB are copied in parts.
+func (o *ObjectStore) ReplaceObjectMetadata(ctx context.Context, bucket, key, contentType string, metadata map[string]string, opts ...ObjectOption) error {
+	escapedKey := strings.ReplaceAll(url.PathEscape(key), "%2F", "/")
+	copySource := fmt.Sprintf("%s/%s", bucket, escapedKey)
+	input := &s3.CopyObjectInput{
+		Bucket:            aws.String(bucket),
+		CopySource:        aws.String(copySource),
+		Key:               aws.String(key),
+		Metadata:          metadata,
+		MetadataDirective: types.MetadataDirectiveReplace,
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	options := applyObjectOptions(opts)
+	if options.ContentDisposition != "" {
+		input.ContentDisposition = aws.String(options.ContentDisposition)
+	}
+	if options.CacheControl != "" {
+		input.CacheControl = aws.String(options.CacheControl)
+	}
+	if options.ServerSideEncryption != "" {
+		input.ServerSideEncryption = types.ServerSideEncryption(options.ServerSideEncryption)
+		if options.ServerSideEncryption == ServerSideEncryptionKMS && options.SSEKMSKeyID != "" {
+			input.SSEKMSKeyId = aws.String(options.SSEKMSKeyID)
+		}
+	}
+	if options.ObjectLockMode != "" {
+		input.ObjectLockMode = types.ObjectLockMode(options.ObjectLockMode)
+		input.ObjectLockRetainUntilDate = aws.Time(options.ObjectLockRetainUntil)
+		// S3 only accepts locked objects with an integrity checksum
+		input.ChecksumAlgorithm = types.ChecksumAlgorithmCrc32
+	}
+
+	_, err := o.client.CopyObject(ctx, input)
+	if err == nil {
+		return nil
+	}
+
+	head, headErr := o.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if headErr != nil || aws.ToInt64(head.ContentLength) <= maxCopyObjectSize {
+		return err
+	}
+	return o.copyObjectMultipart(ctx, copySource, aws.ToInt64(head.ContentLength), &s3.CreateMultipartUploadInput{
+		Bucket:                    input.Bucket,
+		Key:                       input.Key,
+		ContentType:               cmp.Or(input.ContentType, head.ContentType),
+		Metadata:                  input.Metadata,
+		ContentDisposition:        input.ContentDisposition,
+		CacheControl:              input.CacheControl,
+		ServerSideEncryption:      input.ServerSideEncryption,
+		SSEKMSKeyId:               input.SSEKMSKeyId,
+		ObjectLockMode:            input.ObjectLockMode,
+		ObjectLockRetainUntilDate: input.ObjectLockRetainUntilDate,
+		ChecksumAlgorithm:         input.ChecksumAlgorithm,
+	})
+}
+
+// PutObjectTagging replaces the tag set of an existing object
+func (o *ObjectStore) PutObjectTagging(ctx context.Context, bucket, key string, tags map[string]string) error {
+	tagSet := make([]types.Tag, 0, len(tags))
+	for k, v := range tags {
+		tagSet = append(tagSet, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	_, err := o.client.PutObjectTagging(ctx, &s3.PutObjectTaggingInput{
+		Bucket:  aws.String(bucket),
+		Key:     aws.String(key),
+		Tagging: &types.Tagging{TagSet: tagSet},
+	})
 	return err
 }
 
@@ -312,7 +832,12 @@ func (o *ObjectStore) ListAllObjects(ctx context.Context, bucket, prefix string)
 
 // CalculateBucketSize calculates the total size of all objects in a bucket
 func (o *ObjectStore) CalculateBucketSize(ctx context.Context, bucket string) (int64, error) {
-	objects, err := o.ListAllObjects(ctx, bucket, "")
+	return o.CalculatePrefixSize(ctx, bucket, "")
+}
+
+// CalculatePrefixSize calculates the total size of the objects whose keys start with prefix
+func (o *ObjectStore) CalculatePrefixSize(ctx context.Context, bucket, prefix string) (int64, error) {
+	objects, err := o.ListAllObjects(ctx, bucket, prefix)
 	if err != nil {
 		return 0, err
 	}
@@ -36,6 +36,11 @@ UPDATE buckets
 SET size_bytes = $2, updated_at = NOW()
 WHERE id = $1;
 
+-- name: AddBucketSize :exec
+UPDATE buckets
+SET size_bytes = GREATEST(size_bytes + $2, 0), updated_at = NOW()
+WHERE id = $1;
+
 -- name: UpdateBucket :exec
 UPDATE buckets
 SET description = $3, updated_at = NOW()